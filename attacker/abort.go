@@ -0,0 +1,238 @@
+package attacker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nakabonne/ali/storage"
+)
+
+const (
+	DefaultAbortWindow        = 30 * time.Second
+	DefaultAbortCheckInterval = time.Second
+)
+
+// AbortCondition is a condition to abort the attack early, evaluated on a rolling window.
+type AbortCondition struct {
+	// Metric is one of "error_ratio", "mean", "p50", "p90", "p95", "p99" and "max".
+	Metric string
+	// Operator is one of "<", "<=", ">", ">=", "==" and "!=".
+	Operator string
+	// Threshold is compared with the metric; latencies are in nanoseconds.
+	Threshold float64
+	// Window is the range of the data points the metric is computed from.
+	Window time.Duration
+	// Consecutive is the number of consecutive windows the condition has to be met in.
+	Consecutive int
+
+	raw string
+}
+
+// AbortError is the cause of the cancellation when the attack got aborted by an AbortCondition.
+type AbortError struct {
+	// Condition is the condition which was met.
+	Condition string `json:"condition"`
+	// Actual is the observed value of the metric within the window.
+	Actual string `json:"actual"`
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("aborted because %s (actual: %s)", e.Condition, e.Actual)
+}
+
+// ParseAbortCondition parses the given condition in the form of
+// "<metric><operator><threshold>[,window=<duration>][,consecutive=<n>]", like "p99>2s,window=10s,consecutive=3".
+func ParseAbortCondition(s string) (AbortCondition, error) {
+	parts := strings.Split(s, ",")
+	c := AbortCondition{
+		Window:      DefaultAbortWindow,
+		Consecutive: 1,
+		raw:         s,
+	}
+	var found bool
+	for _, op := range operators {
+		i := strings.Index(parts[0], op)
+		if i < 0 {
+			continue
+		}
+		c.Metric = strings.ToLower(strings.TrimSpace(parts[0][:i]))
+		c.Operator = op
+		value := strings.TrimSpace(parts[0][i+len(op):])
+		switch {
+		case c.Metric == "error_ratio":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return AbortCondition{}, fmt.Errorf("threshold of %q must be a number: %w", c.Metric, err)
+			}
+			c.Threshold = v
+		case isLatencyMetric(c.Metric) && c.Metric != "min":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return AbortCondition{}, fmt.Errorf("threshold of %q must be a duration: %w", c.Metric, err)
+			}
+			c.Threshold = float64(d)
+		default:
+			return AbortCondition{}, fmt.Errorf("unknown metric %q", c.Metric)
+		}
+		found = true
+		break
+	}
+	if !found {
+		return AbortCondition{}, fmt.Errorf("no operator found in %q", parts[0])
+	}
+
+	for _, param := range parts[1:] {
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			return AbortCondition{}, fmt.Errorf("given parameter %q has a wrong format", param)
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "window":
+			c.Window, err = time.ParseDuration(strings.TrimSpace(val))
+			if err == nil && c.Window <= 0 {
+				err = fmt.Errorf("window must be greater than 0s")
+			}
+		case "consecutive":
+			c.Consecutive, err = strconv.Atoi(strings.TrimSpace(val))
+			if err == nil && c.Consecutive <= 0 {
+				err = fmt.Errorf("consecutive must be greater than 0")
+			}
+		default:
+			return AbortCondition{}, fmt.Errorf("unknown parameter %q", key)
+		}
+		if err != nil {
+			return AbortCondition{}, fmt.Errorf("given parameter %q has a wrong value: %w", key, err)
+		}
+	}
+	return c, nil
+}
+
+func (c AbortCondition) String() string {
+	if c.raw != "" {
+		return c.raw
+	}
+	threshold := strconv.FormatFloat(c.Threshold, 'f', -1, 64)
+	if c.Metric != "error_ratio" {
+		threshold = time.Duration(c.Threshold).String()
+	}
+	return fmt.Sprintf("%s%s%s,window=%v,consecutive=%d", c.Metric, c.Operator, threshold, c.Window, c.Consecutive)
+}
+
+// evaluate computes the metric out of the data points between start and end,
+// and then reports whether the condition is met.
+// It's never met if there is no data point.
+func (c AbortCondition) evaluate(r storage.Reader, start, end time.Time) (actual string, met bool, err error) {
+	var value float64
+	if c.Metric == "error_ratio" {
+		errs, err := r.Select(storage.ErrorMetricName, start, end)
+		if err != nil {
+			return "", false, err
+		}
+		if len(errs) == 0 {
+			return "", false, nil
+		}
+		var sum float64
+		for _, e := range errs {
+			sum += e
+		}
+		value = sum / float64(len(errs))
+		actual = strconv.FormatFloat(value, 'g', 6, 64)
+	} else {
+		latencies, err := r.Select(storage.LatencyMetricName, start, end)
+		if err != nil {
+			return "", false, err
+		}
+		if len(latencies) == 0 {
+			return "", false, nil
+		}
+		// Latencies are stored in milliseconds.
+		value = latencyOf(c.Metric, latencies) * float64(time.Millisecond)
+		actual = time.Duration(value).String()
+	}
+	return actual, compare(value, c.Operator, c.Threshold), nil
+}
+
+// latencyOf gives back the given statistic of the latencies.
+func latencyOf(metric string, latencies []float64) float64 {
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+	quantile := func(q float64) float64 {
+		i := int(math.Ceil(q*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+	switch metric {
+	case "mean":
+		var sum float64
+		for _, l := range sorted {
+			sum += l
+		}
+		return sum / float64(len(sorted))
+	case "p50":
+		return quantile(0.50)
+	case "p90":
+		return quantile(0.90)
+	case "p95":
+		return quantile(0.95)
+	case "p99":
+		return quantile(0.99)
+	default:
+		return sorted[len(sorted)-1]
+	}
+}
+
+// WatchAbortConditions evaluates the given conditions against the data points in the reader at every interval,
+// and then cancels the attack with an *AbortError as the cause once any of them is met.
+// Each condition isn't evaluated until its full window has passed since it gets started,
+// so that a few early data points alone don't abort the attack.
+func WatchAbortConditions(ctx context.Context, cancel context.CancelCauseFunc, r storage.Reader, conditions []AbortCondition, interval time.Duration) {
+	if len(conditions) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	began := time.Now()
+	// streaks hold the number of consecutive windows each condition has been met in,
+	// and when the latest one was counted.
+	streaks := make([]int, len(conditions))
+	counted := make([]time.Time, len(conditions))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for i, c := range conditions {
+				if now.Sub(began) < c.Window {
+					continue
+				}
+				actual, met, err := c.evaluate(r, now.Add(-c.Window), now)
+				if err != nil {
+					log.Printf("failed to evaluate abort condition %q: %v\n", c, err)
+					continue
+				}
+				if !met {
+					streaks[i] = 0
+					continue
+				}
+				if streaks[i] == 0 || now.Sub(counted[i]) >= c.Window {
+					streaks[i]++
+					counted[i] = now
+				}
+				if streaks[i] >= c.Consecutive {
+					cancel(&AbortError{Condition: c.String(), Actual: actual})
+					return
+				}
+			}
+		}
+	}
+}
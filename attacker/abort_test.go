@@ -0,0 +1,156 @@
+package attacker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nakabonne/ali/storage"
+)
+
+func TestParseAbortCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    AbortCondition
+		wantErr bool
+	}{
+		{
+			name: "error ratio with the default window",
+			raw:  "error_ratio>0.05",
+			want: AbortCondition{Metric: "error_ratio", Operator: ">", Threshold: 0.05, Window: DefaultAbortWindow, Consecutive: 1, raw: "error_ratio>0.05"},
+		},
+		{
+			name: "latency with parameters",
+			raw:  "p99>2s, window=10s, consecutive=3",
+			want: AbortCondition{Metric: "p99", Operator: ">", Threshold: float64(2 * time.Second), Window: 10 * time.Second, Consecutive: 3, raw: "p99>2s, window=10s, consecutive=3"},
+		},
+		{
+			name:    "unknown metric",
+			raw:     "rate<10",
+			wantErr: true,
+		},
+		{
+			name:    "no operator",
+			raw:     "p99,window=10s",
+			wantErr: true,
+		},
+		{
+			name:    "unknown parameter",
+			raw:     "p99>2s,foo=1",
+			wantErr: true,
+		},
+		{
+			name:    "non-positive window",
+			raw:     "p99>2s,window=0s",
+			wantErr: true,
+		},
+		{
+			name:    "non-positive consecutive",
+			raw:     "p99>2s,consecutive=0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAbortCondition(tt.raw)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAbortConditionEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		condition  string
+		values     []float64
+		wantActual string
+		wantMet    bool
+	}{
+		{
+			name:       "error ratio exceeded",
+			condition:  "error_ratio>0.05",
+			values:     []float64{0, 1, 0, 0},
+			wantActual: "0.25",
+			wantMet:    true,
+		},
+		{
+			name:       "p99 not exceeded",
+			condition:  "p99>2s",
+			values:     []float64{1500, 10, 20},
+			wantActual: "1.5s",
+			wantMet:    false,
+		},
+		{
+			name:      "no data points",
+			condition: "max>1s",
+			values:    []float64{},
+			wantMet:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseAbortCondition(tt.condition)
+			require.NoError(t, err)
+			actual, met, err := c.evaluate(&storage.FakeStorage{Values: tt.values}, time.Now().Add(-time.Second), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantActual, actual)
+			assert.Equal(t, tt.wantMet, met)
+		})
+	}
+}
+
+func TestWatchAbortConditions(t *testing.T) {
+	c, err := ParseAbortCondition("error_ratio>=0.5,window=10ms,consecutive=2")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	done := make(chan struct{})
+	go func() {
+		WatchAbortConditions(ctx, cancel, &storage.FakeStorage{Values: []float64{1, 0}}, []AbortCondition{c}, time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the attack wasn't aborted")
+	}
+	var abortErr *AbortError
+	require.True(t, errors.As(context.Cause(ctx), &abortErr))
+	assert.Equal(t, &AbortError{Condition: "error_ratio>=0.5,window=10ms,consecutive=2", Actual: "0.5"}, abortErr)
+}
+
+func TestWatchAbortConditionsEarlyError(t *testing.T) {
+	c, err := ParseAbortCondition("error_ratio>0.5,window=100ms")
+	require.NoError(t, err)
+	s, err := storage.NewStorage(time.Hour)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	done := make(chan struct{})
+	go func() {
+		WatchAbortConditions(ctx, cancel, s, []AbortCondition{c}, time.Millisecond)
+		close(done)
+	}()
+
+	// Only the first request fails, which is far from the half of the window.
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, s.Insert(&storage.Result{Code: 500, Timestamp: time.Now()}))
+	for i := 0; i < 40; i++ {
+		time.Sleep(5 * time.Millisecond)
+		require.NoError(t, s.Insert(&storage.Result{Code: 200, Timestamp: time.Now()}))
+	}
+	cancel(nil)
+	<-done
+
+	var abortErr *AbortError
+	assert.False(t, errors.As(context.Cause(ctx), &abortErr), "aborted by %v", context.Cause(ctx))
+}
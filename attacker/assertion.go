@@ -0,0 +1,162 @@
+package attacker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assertion is a condition the final metrics are expected to satisfy, like "p99<250ms".
+type Assertion struct {
+	// Metric is one of "mean", "p50", "p90", "p95", "p99", "max", "min",
+	// "success", "rate", "throughput" and "requests".
+	Metric string
+	// Operator is one of "<", "<=", ">", ">=", "==" and "!=".
+	Operator string
+	// Threshold is compared with the metric; latencies are in nanoseconds.
+	Threshold float64
+
+	raw string
+}
+
+// CheckResult is the outcome of an assertion.
+type CheckResult struct {
+	// Assertion is the assertion as it was given.
+	Assertion string `json:"assertion"`
+	// Actual is the observed value of the metric.
+	Actual string `json:"actual"`
+	// Passed is true if the metric satisfied the assertion.
+	Passed bool `json:"passed"`
+}
+
+// operators are ordered so that the longer ones are matched first.
+var operators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// ParseAssertion parses the given assertion in the form of "<metric><operator><threshold>".
+// Thresholds of latencies must be durations like "250ms".
+func ParseAssertion(s string) (Assertion, error) {
+	for _, op := range operators {
+		i := strings.Index(s, op)
+		if i < 0 {
+			continue
+		}
+		a := Assertion{
+			Metric:   strings.ToLower(strings.TrimSpace(s[:i])),
+			Operator: op,
+			raw:      s,
+		}
+		value := strings.TrimSpace(s[i+len(op):])
+		if isLatencyMetric(a.Metric) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return Assertion{}, fmt.Errorf("threshold of %q must be a duration: %w", a.Metric, err)
+			}
+			a.Threshold = float64(d)
+			return a, nil
+		}
+		switch a.Metric {
+		case "success", "rate", "throughput", "requests":
+		default:
+			return Assertion{}, fmt.Errorf("unknown metric %q", a.Metric)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Assertion{}, fmt.Errorf("threshold of %q must be a number: %w", a.Metric, err)
+		}
+		a.Threshold = v
+		return a, nil
+	}
+	return Assertion{}, fmt.Errorf("no operator found in %q", s)
+}
+
+func (a Assertion) String() string {
+	if a.raw != "" {
+		return a.raw
+	}
+	threshold := strconv.FormatFloat(a.Threshold, 'f', -1, 64)
+	if isLatencyMetric(a.Metric) {
+		threshold = time.Duration(a.Threshold).String()
+	}
+	return a.Metric + a.Operator + threshold
+}
+
+// Check evaluates the assertion against the given metrics.
+func (a Assertion) Check(m *Metrics) CheckResult {
+	var actual float64
+	switch a.Metric {
+	case "mean":
+		actual = float64(m.Latencies.Mean)
+	case "p50":
+		actual = float64(m.Latencies.P50)
+	case "p90":
+		actual = float64(m.Latencies.P90)
+	case "p95":
+		actual = float64(m.Latencies.P95)
+	case "p99":
+		actual = float64(m.Latencies.P99)
+	case "max":
+		actual = float64(m.Latencies.Max)
+	case "min":
+		actual = float64(m.Latencies.Min)
+	case "success":
+		actual = m.Success
+	case "rate":
+		actual = m.Rate
+	case "throughput":
+		actual = m.Throughput
+	case "requests":
+		actual = float64(m.Requests)
+	}
+
+	res := CheckResult{Assertion: a.String()}
+	switch {
+	case isLatencyMetric(a.Metric):
+		res.Actual = time.Duration(actual).String()
+	case a.Metric == "requests":
+		res.Actual = strconv.FormatUint(m.Requests, 10)
+	default:
+		res.Actual = strconv.FormatFloat(actual, 'g', 6, 64)
+	}
+	res.Passed = compare(actual, a.Operator, a.Threshold)
+	return res
+}
+
+// compare reports whether "x <operator> y" holds.
+func compare(x float64, operator string, y float64) bool {
+	switch operator {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	}
+	return false
+}
+
+func isLatencyMetric(metric string) bool {
+	switch metric {
+	case "mean", "p50", "p90", "p95", "p99", "max", "min":
+		return true
+	}
+	return false
+}
+
+// checkAll evaluates all the given assertions against the given metrics.
+func checkAll(assertions []Assertion, m *Metrics) []CheckResult {
+	if len(assertions) == 0 {
+		return nil
+	}
+	results := make([]CheckResult, 0, len(assertions))
+	for _, a := range assertions {
+		results = append(results, a.Check(m))
+	}
+	return results
+}
@@ -0,0 +1,87 @@
+package attacker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Assertion
+		wantErr bool
+	}{
+		{
+			name: "latency",
+			raw:  "p99<250ms",
+			want: Assertion{Metric: "p99", Operator: "<", Threshold: float64(250 * time.Millisecond), raw: "p99<250ms"},
+		},
+		{
+			name: "ratio with spaces",
+			raw:  "success >= 0.999",
+			want: Assertion{Metric: "success", Operator: ">=", Threshold: 0.999, raw: "success >= 0.999"},
+		},
+		{
+			name: "upper case metric",
+			raw:  "Rate>=95",
+			want: Assertion{Metric: "rate", Operator: ">=", Threshold: 95, raw: "Rate>=95"},
+		},
+		{
+			name:    "no operator",
+			raw:     "p99",
+			wantErr: true,
+		},
+		{
+			name:    "unknown metric",
+			raw:     "p42<1s",
+			wantErr: true,
+		},
+		{
+			name:    "latency without unit",
+			raw:     "p99<250",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric threshold",
+			raw:     "success>high",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAssertion(tt.raw)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	m := &Metrics{
+		Latencies: LatencyMetrics{P99: 300 * time.Millisecond},
+		Success:   0.9995,
+		Rate:      94.5,
+		Requests:  1000,
+	}
+	tests := []struct {
+		raw  string
+		want CheckResult
+	}{
+		{"p99<250ms", CheckResult{Assertion: "p99<250ms", Actual: "300ms", Passed: false}},
+		{"p99<=300ms", CheckResult{Assertion: "p99<=300ms", Actual: "300ms", Passed: true}},
+		{"success>=0.999", CheckResult{Assertion: "success>=0.999", Actual: "0.9995", Passed: true}},
+		{"rate>=95", CheckResult{Assertion: "rate>=95", Actual: "94.5", Passed: false}},
+		{"requests==1000", CheckResult{Assertion: "requests==1000", Actual: "1000", Passed: true}},
+		{"requests!=1000", CheckResult{Assertion: "requests!=1000", Actual: "1000", Passed: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			a, err := ParseAssertion(tt.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, a.Check(m))
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package attacker
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	vegeta "github.com/tsenart/vegeta/v12/lib"
@@ -32,6 +36,18 @@ const (
 
 var DefaultLocalAddr = net.IPAddr{IP: net.IPv4zero}
 
+// breakdownInterval is how often the per-target and per-stage metrics are rebuilt while attacking,
+// which is as often as the charts are redrawn by default.
+const breakdownInterval = 250 * time.Millisecond
+
+// ErrStopped is the cause of the cancellation when the attack got stopped by the user.
+// The attack stopped with it is finalized as usual, unlike the one simply canceled.
+var ErrStopped = errors.New("attack stopped")
+
+// ErrInterrupted is the cause of the cancellation when the attack got interrupted by a signal.
+// It's finalized in the same way as ErrStopped.
+var ErrInterrupted = errors.New("attack interrupted")
+
 // Options provides optional settings to attack.
 type Options struct {
 	Rate        int
@@ -47,8 +63,20 @@ type Options struct {
 	Connections int
 	HTTP2       bool
 	LocalAddr   net.IPAddr
-	Buckets     []time.Duration
-	Resolvers   []string
+	// Buckets are the lower bounds of the latency histogram buckets in ascending order.
+	// The latencies lower than the first one are counted in the last bucket, in the same way as vegeta.
+	Buckets   []time.Duration
+	Resolvers []string
+	// Targets are used instead of the single target if given.
+	Targets []Target
+	// TargetSelection is how to pick one of Targets for each request; "round-robin" or "weighted".
+	TargetSelection string
+	// LoadProfile changes the rate over time. Rate is used if it's the zero value.
+	LoadProfile LoadProfile
+	// Stages are run back-to-back if given, instead of attacking at Rate for Duration.
+	Stages []Stage
+	// Assertions are evaluated against the final metrics of each attack.
+	Assertions []Assertion
 
 	InsecureSkipVerify bool
 	CACertificatePool  *x509.CertPool
@@ -56,8 +84,12 @@ type Options struct {
 
 	Attacker backedAttacker
 
-	Exporter    *export.FileExporter
+	Exporter    export.Exporter
 	IDGenerator func() string
+	// Config is exported along with each run, with the credentials redacted.
+	Config *Config
+	// Baseline is compared with each attack in its summary.
+	Baseline *export.Baseline
 }
 
 type Attacker interface {
@@ -73,15 +105,78 @@ type Attacker interface {
 	Duration() time.Duration
 	// Rate gives back the method set to itself.
 	Method() string
+	// LoadProfile gives back the load profile set to itself.
+	LoadProfile() LoadProfile
+	// TargetRate gives back the rate per second the ongoing attack is aiming at.
+	// It gives back the initial one if no attack is running.
+	TargetRate() float64
+	// Stages gives back the stages set to itself.
+	Stages() []Stage
+	// Buckets gives back the lower bounds of the latency histogram buckets set to itself.
+	Buckets() []time.Duration
+	// Checks gives back the results of the assertions against the last finished attack.
+	Checks() []CheckResult
+	// Pause stops issuing requests of the ongoing attack until Resume is called.
+	Pause()
+	// Resume resumes the paused attack.
+	Resume()
+	// Paused reports whether the ongoing attack is paused.
+	Paused() bool
+	// SetRate changes the rate of the ongoing attack to the given one, until the current stage ends if any.
+	SetRate(rate int) error
+	// InFlight gives back the number of requests issued but not completed yet, which is the number of busy workers.
+	InFlight() uint64
 }
 
 func NewAttacker(storage storage.Writer, target string, opts *Options) (Attacker, error) {
-	if target == "" {
-		return nil, fmt.Errorf("target is required")
-	}
 	if opts == nil {
 		opts = &Options{}
 	}
+	if target == "" && len(opts.Targets) == 0 {
+		for _, s := range opts.Stages {
+			if len(s.Targets) == 0 {
+				return nil, fmt.Errorf("target is required")
+			}
+		}
+		if len(opts.Stages) == 0 {
+			return nil, fmt.Errorf("target is required")
+		}
+	}
+	if target == "" {
+		// The first one is used as the representative of the targets.
+		if len(opts.Targets) > 0 {
+			target = opts.Targets[0].URL
+		} else {
+			target = opts.Stages[0].Targets[0].URL
+		}
+	}
+	if len(opts.Targets) > 0 {
+		if _, err := newTargeter(opts.Targets, opts.TargetSelection); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Stages) > 0 {
+		if opts.LoadProfile.Type != "" && opts.LoadProfile.Type != LoadProfileConstant {
+			return nil, fmt.Errorf("load profile can't be used along with stages")
+		}
+		// The duration is the whole one, and the rate is the initial one.
+		opts.Rate = opts.Stages[0].Rate
+		opts.Duration = 0
+		for _, s := range opts.Stages {
+			if s.Duration <= 0 {
+				return nil, fmt.Errorf("duration of stage %q must be greater than 0s", s.Name)
+			}
+			opts.Duration += s.Duration
+			if len(s.Targets) > 0 {
+				if _, err := newTargeter(s.Targets, s.TargetSelection); err != nil {
+					return nil, fmt.Errorf("bad stage %q: %w", s.Name, err)
+				}
+			}
+		}
+	}
+	if _, err := opts.LoadProfile.pacer(opts.Rate, opts.Duration); err != nil {
+		return nil, err
+	}
 	if opts.Method == "" {
 		opts.Method = DefaultMethod
 	}
@@ -111,18 +206,37 @@ func NewAttacker(storage storage.Writer, target string, opts *Options) (Attacker
 	}
 	tlsConfig.BuildNameToCertificate()
 
+	// A vegeta attacker can't be reused once stopped, so a fresh one is made for every attack.
+	var newBackedAttacker func() backedAttacker
 	if opts.Attacker == nil {
-		opts.Attacker = vegeta.NewAttacker(
-			vegeta.Timeout(opts.Timeout),
-			vegeta.Workers(opts.Workers),
-			vegeta.MaxWorkers(opts.MaxWorkers),
-			vegeta.MaxBody(opts.MaxBody),
-			vegeta.Connections(opts.Connections),
-			vegeta.KeepAlive(opts.KeepAlive),
-			vegeta.HTTP2(opts.HTTP2),
-			vegeta.LocalAddr(opts.LocalAddr),
-			vegeta.TLSConfig(tlsConfig),
-		)
+		newBackedAttacker = func() backedAttacker {
+			return vegeta.NewAttacker(
+				vegeta.Timeout(opts.Timeout),
+				vegeta.Workers(opts.Workers),
+				vegeta.MaxWorkers(opts.MaxWorkers),
+				vegeta.MaxBody(opts.MaxBody),
+				vegeta.Connections(opts.Connections),
+				vegeta.KeepAlive(opts.KeepAlive),
+				vegeta.HTTP2(opts.HTTP2),
+				vegeta.LocalAddr(opts.LocalAddr),
+				vegeta.TLSConfig(tlsConfig),
+			)
+		}
+	}
+	var config []byte
+	if opts.Config != nil {
+		redacted, err := opts.Config.Redacted()
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact config: %w", err)
+		}
+		// Not escaped, so that the placeholders can be replaced by hand.
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(redacted); err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+		config = b.Bytes()
 	}
 	return &attacker{
 		target:             target,
@@ -141,13 +255,21 @@ func NewAttacker(storage storage.Writer, target string, opts *Options) (Attacker
 		localAddr:          opts.LocalAddr,
 		buckets:            opts.Buckets,
 		resolvers:          opts.Resolvers,
+		targets:            opts.Targets,
+		targetSelection:    opts.TargetSelection,
+		loadProfile:        opts.LoadProfile,
+		stages:             opts.Stages,
+		assertions:         opts.Assertions,
 		insecureSkipVerify: opts.InsecureSkipVerify,
 		caCertificatePool:  opts.CACertificatePool,
 		tlsCertificates:    opts.TLSCertificates,
 		attacker:           opts.Attacker,
+		newBackedAttacker:  newBackedAttacker,
 		storage:            storage,
 		exporter:           opts.Exporter,
 		idGenerator:        opts.IDGenerator,
+		baseline:           opts.Baseline,
+		config:             config,
 	}, nil
 }
 
@@ -173,36 +295,56 @@ type attacker struct {
 	localAddr          net.IPAddr
 	buckets            []time.Duration
 	resolvers          []string
+	targets            []Target
+	targetSelection    string
+	loadProfile        LoadProfile
+	stages             []Stage
+	assertions         []Assertion
 	insecureSkipVerify bool
 	caCertificatePool  *x509.CertPool
 	tlsCertificates    []tls.Certificate
 
-	attacker backedAttacker
-	storage  storage.Writer
+	attacker          backedAttacker
+	newBackedAttacker func() backedAttacker
+	storage           storage.Writer
 
-	exporter    *export.FileExporter
+	exporter    export.Exporter
 	idGenerator func() string
+	baseline    *export.Baseline
+	// config is the redacted config in JSON, exported along with each run.
+	config []byte
+
+	// pacer is the one used by the ongoing attack. pacerMu also guards attacker,
+	// which gets replaced when the next attack starts.
+	pacerMu sync.RWMutex
+	pacer   *pausablePacer
+	pauser  *pauser
+	// events are the ones happened during the ongoing attack.
+	events []export.Event
+
+	checksMu sync.RWMutex
+	checks   []CheckResult
 }
 
 func (a *attacker) Attack(ctx context.Context, metricsCh chan *Metrics) error {
-	rate := vegeta.Rate{Freq: a.rate, Per: time.Second}
-	targeter := vegeta.NewStaticTargeter(vegeta.Target{
-		Method: a.method,
-		URL:    a.target,
-		Body:   a.body,
-		Header: a.header,
-	})
+	phases, err := a.phases()
+	if err != nil {
+		return err
+	}
+	backed := a.backedAttacker()
 
 	metrics := &vegeta.Metrics{}
 	if len(a.buckets) > 0 {
 		metrics.Histogram = &vegeta.Histogram{Buckets: a.buckets}
 	}
+	targetMetrics := make(map[targetKey]*vegeta.Metrics)
+	var stageMetrics []*vegeta.Metrics
 	idGenerator := a.idGenerator
 	if idGenerator == nil {
 		idGenerator = defaultIDGenerator
 	}
 
-	var runExporter *export.Run
+	var runExporter export.RunExporter
 	if a.exporter != nil {
 		var err error
 		runExporter, err = a.exporter.StartRun(export.Meta{
@@ -211,62 +353,361 @@ func (a *attacker) Attack(ctx context.Context, metricsCh chan *Metrics) error {
 			Method:    a.method,
 			Rate:      a.rate,
 			Duration:  a.duration,
+			StartedAt: time.Now(),
+			Config:    a.config,
 		})
 		if err != nil {
 			return err
 		}
 	}
 
-	for res := range a.attacker.Attack(targeter, rate, a.duration, "main") {
-		select {
-		case <-ctx.Done():
-			a.attacker.Stop()
-			if runExporter != nil {
-				_ = runExporter.Abort()
-			}
-			return nil
-		default:
-			metrics.Add(res)
-			m := newMetrics(metrics)
-			err := a.storage.Insert(&storage.Result{
-				Code:      res.Code,
-				Timestamp: res.Timestamp,
-				Latency:   res.Latency,
-				P50:       m.Latencies.P50,
-				P90:       m.Latencies.P90,
-				P95:       m.Latencies.P95,
-				P99:       m.Latencies.P99,
-			})
-			if err != nil {
-				log.Printf("failed to insert results")
-				continue
-			}
-			if runExporter != nil {
-				if err := runExporter.WriteResult(export.Result{
+	// targets and stages are the latest snapshots of the per-target and per-stage metrics,
+	// which are too costly to rebuild on every result.
+	var (
+		targets       []TargetMetrics
+		stages        []StageMetrics
+		lastBreakdown time.Time
+	)
+	pauser := &pauser{}
+	a.setPauser(pauser)
+	began := time.Now()
+	defer a.release(pauser)
+L:
+	for _, ph := range phases {
+		// The duration is taken care of by the pacer, so that the pauses aren't counted.
+		pacer := newPausablePacer(ph.pacer, ph.duration, pauser, ctx.Done())
+		a.setPacer(pacer)
+		sm := &vegeta.Metrics{}
+		if ph.stage > 0 {
+			stageMetrics = append(stageMetrics, sm)
+		}
+		for res := range backed.Attack(ph.targeter, pacer, 0, ph.name) {
+			select {
+			case <-ctx.Done():
+				backed.Stop()
+				break L
+			default:
+				pacer.complete()
+				metrics.Add(res)
+				key := a.targetKeyOf(res)
+				tm, ok := targetMetrics[key]
+				if !ok {
+					tm = &vegeta.Metrics{}
+					targetMetrics[key] = tm
+				}
+				tm.Add(res)
+				sm.Add(res)
+				// Compute the derived metrics like success ratio on a per-result basis,
+				// so that they can be seen while attacking.
+				metrics.Close()
+				if now := time.Now(); now.Sub(lastBreakdown) >= breakdownInterval {
+					targets, stages = a.breakdown(targetMetrics, stageMetrics)
+					lastBreakdown = now
+				}
+				m := newMetrics(metrics)
+				m.excludePause(pauser.pausedFor())
+				m.Targets = targets
+				m.Stages = stages
+				err := a.storage.Insert(&storage.Result{
+					Code:       res.Code,
 					Timestamp:  res.Timestamp,
-					LatencyNS:  float64(res.Latency.Nanoseconds()),
-					URL:        a.target,
-					Method:     a.method,
-					StatusCode: res.Code,
-				}); err != nil {
-					_ = runExporter.Abort()
-					return err
+					Latency:    res.Latency,
+					P50:        m.Latencies.P50,
+					P90:        m.Latencies.P90,
+					P95:        m.Latencies.P95,
+					P99:        m.Latencies.P99,
+					Stage:      ph.stage,
+					TargetRate: pacer.currentRate(),
+				})
+				if err != nil {
+					log.Printf("failed to insert results")
+					continue
+				}
+				if runExporter != nil {
+					if err := runExporter.WriteResult(export.Result{
+						Timestamp:  res.Timestamp,
+						LatencyNS:  float64(res.Latency.Nanoseconds()),
+						URL:        res.URL,
+						Method:     res.Method,
+						StatusCode: res.Code,
+						Seq:        res.Seq,
+						Attack:     res.Attack,
+						BytesIn:    res.BytesIn,
+						BytesOut:   res.BytesOut,
+						Error:      res.Error,
+						Headers:    res.Headers,
+					}); err != nil {
+						_ = runExporter.Abort()
+						return err
+					}
 				}
+				metricsCh <- m
 			}
-			metricsCh <- m
 		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	// abortErr is set if the attack got aborted by an abort condition.
+	var abortErr *AbortError
+	stopped := errors.Is(context.Cause(ctx), ErrStopped) || errors.Is(context.Cause(ctx), ErrInterrupted)
+	if ctx.Err() != nil && !errors.As(context.Cause(ctx), &abortErr) && !stopped {
+		if runExporter != nil {
+			_ = runExporter.Abort()
+		}
+		return nil
 	}
+	// Finalize as usual even if aborted or stopped, to keep the results so far along with the reason.
 	metrics.Close()
 	finalMetrics := newMetrics(metrics)
+	finalMetrics.excludePause(pauser.pausedFor())
+	finalMetrics.Targets, finalMetrics.Stages = a.breakdown(targetMetrics, stageMetrics)
+	finalMetrics.Aborted = abortErr
+	finalMetrics.Stopped = stopped
+	finalMetrics.Checks = checkAll(a.assertions, finalMetrics)
+	a.checksMu.Lock()
+	a.checks = finalMetrics.Checks
+	a.checksMu.Unlock()
 	metricsCh <- finalMetrics
 	if runExporter != nil {
-		if err := runExporter.Close(newSummary(a.target, a.method, a.rate, a.duration, finalMetrics)); err != nil {
+		summary := newSummary(a.target, a.method, a.rate, a.duration, finalMetrics)
+		if a.loadProfile.Type != "" && a.loadProfile.Type != LoadProfileConstant {
+			summary.Parameters.LoadProfile = a.loadProfile.String()
+		}
+		summary.Events = a.takeEvents(began)
+		summary.Stopped = stopped
+		if a.baseline != nil {
+			summary.Comparison = a.baseline.Compare(summary)
+		}
+		if abortErr != nil {
+			summary.Aborted = &export.AbortSummary{
+				Condition: abortErr.Condition,
+				Actual:    abortErr.Actual,
+			}
+		}
+		if err := runExporter.Close(summary); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// phase is a unit of attack performed by the backed attacker at a time.
+type phase struct {
+	name string
+	// stage is the 1-based index of the stage, zero for an attack without stages.
+	stage    int
+	duration time.Duration
+	pacer    vegeta.Pacer
+	targeter vegeta.Targeter
+}
+
+// phases gives back the phases to be run back-to-back.
+func (a *attacker) phases() ([]phase, error) {
+	if len(a.stages) == 0 {
+		pacer, err := a.loadProfile.pacer(a.rate, a.duration)
+		if err != nil {
+			return nil, err
+		}
+		targeter, err := a.targeter()
+		if err != nil {
+			return nil, err
+		}
+		return []phase{{name: "main", duration: a.duration, pacer: pacer, targeter: targeter}}, nil
+	}
+
+	defaults := a.targets
+	if len(defaults) == 0 {
+		defaults = []Target{{Method: a.method, URL: a.target, Body: a.body, Header: a.header}}
+	}
+	phases := make([]phase, 0, len(a.stages))
+	for i, s := range a.stages {
+		selection := s.TargetSelection
+		if len(s.Targets) == 0 {
+			selection = a.targetSelection
+		}
+		targeter, err := newTargeter(stageTargets(s, defaults), selection)
+		if err != nil {
+			return nil, fmt.Errorf("bad stage %q: %w", s.Name, err)
+		}
+		phases = append(phases, phase{
+			name:     s.Name,
+			stage:    i + 1,
+			duration: s.Duration,
+			pacer:    vegeta.Rate{Freq: s.Rate, Per: time.Second},
+			targeter: targeter,
+		})
+	}
+	return phases, nil
+}
+
+func (a *attacker) targeter() (vegeta.Targeter, error) {
+	if len(a.targets) == 0 {
+		return vegeta.NewStaticTargeter(vegeta.Target{
+			Method: a.method,
+			URL:    a.target,
+			Body:   a.body,
+			Header: a.header,
+		}), nil
+	}
+	return newTargeter(a.targets, a.targetSelection)
+}
+
+// breakdown closes the given per-target and per-stage metrics and gives back their snapshots.
+func (a *attacker) breakdown(targetMetrics map[targetKey]*vegeta.Metrics, stageMetrics []*vegeta.Metrics) ([]TargetMetrics, []StageMetrics) {
+	for _, m := range targetMetrics {
+		m.Close()
+	}
+	for _, m := range stageMetrics {
+		m.Close()
+	}
+	return newTargetMetrics(targetMetrics), newStageMetrics(a.stages, stageMetrics)
+}
+
+// targetKeyOf gives back the key of the target the given result came from.
+func (a *attacker) targetKeyOf(res *vegeta.Result) targetKey {
+	key := targetKey{method: res.Method, url: res.URL}
+	if key.method == "" {
+		key.method = a.method
+	}
+	if key.url == "" {
+		key.url = a.target
+	}
+	return key
+}
+
+// backedAttacker gives back the backed attacker for a new attack, which is made every time if newBackedAttacker is given.
+func (a *attacker) backedAttacker() backedAttacker {
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	if a.newBackedAttacker != nil {
+		a.attacker = a.newBackedAttacker()
+	}
+	return a.attacker
+}
+
+// setPacer sets the pacer of the attack it belongs to, unless another attack has been started since then.
+func (a *attacker) setPacer(p *pausablePacer) {
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	if a.pauser != p.pauser {
+		return
+	}
+	a.pacer = p
+}
+
+func (a *attacker) setPauser(p *pauser) {
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	a.pauser = p
+	a.events = nil
+}
+
+// release clears the pacer and the pauser if they are still the ones of the attack with the given pauser,
+// so that an attack finishing late doesn't clear the ones of the next attack started in the meantime.
+func (a *attacker) release(p *pauser) {
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	if a.pauser != p {
+		return
+	}
+	a.pauser = nil
+	a.pacer = nil
+	a.events = nil
+}
+
+// takeEvents gives back the events happened so far with the elapsed time since the given one, and then clears them.
+func (a *attacker) takeEvents(began time.Time) []export.Event {
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	events := a.events
+	a.events = nil
+	for i := range events {
+		events[i].ElapsedSeconds = events[i].Timestamp.Sub(began).Seconds()
+	}
+	return events
+}
+
+func (a *attacker) Pause() {
+	a.pacerMu.RLock()
+	defer a.pacerMu.RUnlock()
+	if a.pauser != nil {
+		a.pauser.pause()
+	}
+}
+
+func (a *attacker) Resume() {
+	a.pacerMu.RLock()
+	defer a.pacerMu.RUnlock()
+	if a.pauser != nil {
+		a.pauser.resume()
+	}
+}
+
+func (a *attacker) Paused() bool {
+	a.pacerMu.RLock()
+	defer a.pacerMu.RUnlock()
+	return a.pauser != nil && a.pauser.isPaused()
+}
+
+func (a *attacker) TargetRate() float64 {
+	a.pacerMu.RLock()
+	defer a.pacerMu.RUnlock()
+	if a.pacer == nil {
+		p, err := a.loadProfile.pacer(a.rate, a.duration)
+		if err != nil {
+			return 0
+		}
+		return p.Rate(0)
+	}
+	return a.pacer.currentRate()
+}
+
+func (a *attacker) InFlight() uint64 {
+	a.pacerMu.RLock()
+	defer a.pacerMu.RUnlock()
+	if a.pacer == nil {
+		return 0
+	}
+	return a.pacer.inFlight()
+}
+
+func (a *attacker) SetRate(rate int) error {
+	if rate <= 0 {
+		return fmt.Errorf("rate must be greater than 0")
+	}
+	a.pacerMu.Lock()
+	defer a.pacerMu.Unlock()
+	if a.pacer == nil {
+		return fmt.Errorf("no attack is running")
+	}
+	a.pacer.setRate(rate)
+	a.events = append(a.events, export.Event{
+		Timestamp: time.Now(),
+		Type:      export.EventTypeRateChange,
+		Rate:      rate,
+	})
+	return nil
+}
+
+func (a *attacker) LoadProfile() LoadProfile {
+	return a.loadProfile
+}
+
+func (a *attacker) Stages() []Stage {
+	return a.stages
+}
+
+func (a *attacker) Buckets() []time.Duration {
+	return a.buckets
+}
+
+func (a *attacker) Checks() []CheckResult {
+	a.checksMu.RLock()
+	defer a.checksMu.RUnlock()
+	return a.checks
+}
+
 func (a *attacker) Rate() int {
 	return a.rate
 }
@@ -2,13 +2,19 @@ package attacker
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	vegeta "github.com/tsenart/vegeta/v12/lib"
 	"go.uber.org/goleak"
 
+	"github.com/nakabonne/ali/export"
 	"github.com/nakabonne/ali/storage"
 )
 
@@ -28,6 +34,56 @@ func TestNewAttacker(t *testing.T) {
 			target:  "",
 			wantErr: true,
 		},
+		{
+			name:   "targets given instead",
+			target: "",
+			opts: Options{
+				Targets: []Target{{Method: "GET", URL: "http://host.xz", Weight: 1}},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "unknown target selection",
+			target: "",
+			opts: Options{
+				Targets:         []Target{{Method: "GET", URL: "http://host.xz", Weight: 1}},
+				TargetSelection: "unknown",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "stages with their own targets given instead",
+			target: "",
+			opts: Options{
+				Stages: []Stage{{Name: "a", Rate: 1, Duration: time.Second, Targets: []Target{{Method: "GET", URL: "http://host.xz", Weight: 1}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "stage without targets given",
+			target: "",
+			opts: Options{
+				Stages: []Stage{{Name: "a", Rate: 1, Duration: time.Second}},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "stage without duration given",
+			target: "http://host.xz",
+			opts: Options{
+				Stages: []Stage{{Name: "a", Rate: 1}},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "stages along with load profile given",
+			target: "http://host.xz",
+			opts: Options{
+				Stages:      []Stage{{Name: "a", Rate: 1, Duration: time.Second}},
+				LoadProfile: LoadProfile{Type: LoadProfileLinear, From: 1, To: 10},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -83,3 +139,342 @@ func TestAttack(t *testing.T) {
 		})
 	}
 }
+
+func TestAttackExportsPerRequestTarget(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewAttacker(&storage.FakeStorage{}, "", &Options{
+		Targets: []Target{
+			{Method: "GET", URL: "http://host.xz/a", Weight: 1},
+			{Method: "POST", URL: "http://host.xz/b", Weight: 1},
+		},
+		Attacker: &fakeBackedAttacker{
+			results: []*vegeta.Result{
+				{Code: 200, Method: "GET", URL: "http://host.xz/a"},
+				{Code: 200, Method: "POST", URL: "http://host.xz/b"},
+			},
+		},
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+	})
+	require.NoError(t, err)
+	err = a.Attack(context.Background(), make(chan *Metrics, 100))
+	require.NoError(t, err)
+
+	f, err := os.Open(filepath.Join(dir, "results.csv"))
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"http://host.xz/a", "GET"}, records[1][3:5])
+	assert.Equal(t, []string{"http://host.xz/b", "POST"}, records[2][3:5])
+}
+
+func TestAttackPerTargetMetrics(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewAttacker(&storage.FakeStorage{}, "", &Options{
+		Targets: []Target{
+			{Method: "GET", URL: "http://host.xz/a", Weight: 1},
+			{Method: "POST", URL: "http://host.xz/b", Weight: 1},
+		},
+		Attacker: &fakeBackedAttacker{
+			results: []*vegeta.Result{
+				{Code: 200, Method: "POST", URL: "http://host.xz/b", Latency: 3 * time.Millisecond},
+				{Code: 200, Method: "GET", URL: "http://host.xz/a", Latency: time.Millisecond},
+				{Code: 500, Method: "GET", URL: "http://host.xz/a", Latency: time.Millisecond, Error: "500 Internal Server Error"},
+			},
+		},
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+	})
+	require.NoError(t, err)
+	metricsCh := make(chan *Metrics, 100)
+	require.NoError(t, a.Attack(context.Background(), metricsCh))
+	close(metricsCh)
+
+	var all []*Metrics
+	for m := range metricsCh {
+		all = append(all, m)
+	}
+	require.Len(t, all, 4)
+	// The per-target metrics are rebuilt only once in a while during the attack.
+	assert.Len(t, all[1].Targets, 1)
+	final := all[len(all)-1]
+	require.Len(t, final.Targets, 2)
+	assert.Equal(t, "http://host.xz/a", final.Targets[0].URL)
+	assert.Equal(t, uint64(2), final.Targets[0].Requests)
+	assert.Equal(t, 0.5, final.Targets[0].Success)
+	assert.Equal(t, "http://host.xz/b", final.Targets[1].URL)
+	assert.Equal(t, uint64(1), final.Targets[1].Requests)
+	assert.Equal(t, 3*time.Millisecond, final.Targets[1].Latencies.P99)
+
+	content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+	require.NoError(t, err)
+	var summary export.Summary
+	require.NoError(t, json.Unmarshal(content, &summary))
+	require.Len(t, summary.Targets, 2)
+	assert.Equal(t, "POST", summary.Targets[1].Method)
+	assert.Equal(t, uint64(1), summary.Targets[1].Requests.Count)
+	assert.Equal(t, 3.0, summary.Targets[1].LatencyMS.P99)
+	assert.Equal(t, []string{"500 Internal Server Error"}, summary.Errors)
+}
+
+func TestAttackHistogram(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewAttacker(&storage.FakeStorage{}, "http://host.xz", &Options{
+		Buckets: []time.Duration{0, 10 * time.Millisecond, 100 * time.Millisecond},
+		Attacker: &fakeBackedAttacker{
+			results: []*vegeta.Result{
+				{Code: 200, Latency: time.Millisecond},
+				{Code: 200, Latency: 10 * time.Millisecond},
+				{Code: 200, Latency: 50 * time.Millisecond},
+				{Code: 200, Latency: time.Second},
+			},
+		},
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+	})
+	require.NoError(t, err)
+	metricsCh := make(chan *Metrics, 100)
+	require.NoError(t, a.Attack(context.Background(), metricsCh))
+	close(metricsCh)
+
+	var first, final *Metrics
+	for m := range metricsCh {
+		if first == nil {
+			first = m
+		}
+		final = m
+	}
+	// The counts as of each result are kept as they were.
+	assert.Equal(t, []HistogramBucket{
+		{Min: 0, Max: 10 * time.Millisecond, Count: 1},
+		{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Count: 0},
+		{Min: 100 * time.Millisecond, Count: 0},
+	}, first.Histogram)
+	assert.Equal(t, []HistogramBucket{
+		{Min: 0, Max: 10 * time.Millisecond, Count: 1},
+		{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Count: 2},
+		{Min: 100 * time.Millisecond, Count: 1},
+	}, final.Histogram)
+
+	content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+	require.NoError(t, err)
+	var summary export.Summary
+	require.NoError(t, json.Unmarshal(content, &summary))
+	assert.Equal(t, []export.HistogramBucketSummary{
+		{MinMS: 0, MaxMS: 10, Count: 1},
+		{MinMS: 10, MaxMS: 100, Count: 2},
+		{MinMS: 100, Count: 1},
+	}, summary.Histogram)
+}
+
+func TestAttackStages(t *testing.T) {
+	dir := t.TempDir()
+	backed := &fakeBackedAttacker{
+		results: []*vegeta.Result{
+			{Code: 200, Latency: time.Millisecond},
+			{Code: 500, Latency: 2 * time.Millisecond},
+		},
+	}
+	a, err := NewAttacker(&storage.FakeStorage{}, "http://host.xz", &Options{
+		Stages: []Stage{
+			{Name: "warm-up", Rate: 20, Duration: 30 * time.Second},
+			{Name: "spike", Rate: 1000, Duration: 10 * time.Second},
+		},
+		Assertions: []Assertion{
+			{Metric: "requests", Operator: "==", Threshold: 4},
+			{Metric: "success", Operator: ">=", Threshold: 0.999},
+		},
+		Attacker:    backed,
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 20, a.Rate())
+	assert.Equal(t, 40*time.Second, a.Duration())
+
+	metricsCh := make(chan *Metrics, 100)
+	require.NoError(t, a.Attack(context.Background(), metricsCh))
+	close(metricsCh)
+	assert.Equal(t, []string{"warm-up", "spike"}, backed.names)
+
+	var final *Metrics
+	for m := range metricsCh {
+		final = m
+	}
+	assert.Equal(t, uint64(4), final.Requests)
+	require.Len(t, final.Stages, 2)
+	assert.Equal(t, "spike", final.Stages[1].Name)
+	assert.Equal(t, 1000, final.Stages[1].Rate)
+	assert.Equal(t, uint64(2), final.Stages[1].Requests)
+	assert.Equal(t, 0.5, final.Stages[1].Success)
+
+	content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+	require.NoError(t, err)
+	var summary export.Summary
+	require.NoError(t, json.Unmarshal(content, &summary))
+	require.Len(t, summary.Stages, 2)
+	assert.Equal(t, "warm-up", summary.Stages[0].Name)
+	assert.Equal(t, 30.0, summary.Stages[0].DurationSeconds)
+	assert.Equal(t, uint64(2), summary.Stages[0].Requests.Count)
+
+	// The assertions are evaluated against the metrics of all stages.
+	want := []CheckResult{
+		{Assertion: "requests==4", Actual: "4", Passed: true},
+		{Assertion: "success>=0.999", Actual: "0.5", Passed: false},
+	}
+	assert.Equal(t, want, a.Checks())
+	assert.Equal(t, []export.CheckSummary{
+		{Assertion: "requests==4", Actual: "4", Passed: true},
+		{Assertion: "success>=0.999", Actual: "0.5", Passed: false},
+	}, summary.Checks)
+}
+
+func TestAttackAborted(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewAttacker(&storage.FakeStorage{}, "http://host.xz", &Options{
+		Attacker: &fakeBackedAttacker{
+			results: []*vegeta.Result{{Code: 500}},
+		},
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(&AbortError{Condition: "error_ratio>0.05", Actual: "1"})
+	metricsCh := make(chan *Metrics, 100)
+	require.NoError(t, a.Attack(ctx, metricsCh))
+	close(metricsCh)
+
+	var final *Metrics
+	for m := range metricsCh {
+		final = m
+	}
+	assert.Equal(t, &AbortError{Condition: "error_ratio>0.05", Actual: "1"}, final.Aborted)
+
+	content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+	require.NoError(t, err)
+	var summary export.Summary
+	require.NoError(t, json.Unmarshal(content, &summary))
+	assert.Equal(t, &export.AbortSummary{Condition: "error_ratio>0.05", Actual: "1"}, summary.Aborted)
+}
+
+func TestAttackStopped(t *testing.T) {
+	tests := []struct {
+		name  string
+		cause error
+	}{
+		{
+			name:  "stopped by the user",
+			cause: ErrStopped,
+		},
+		{
+			name:  "interrupted by a signal",
+			cause: ErrInterrupted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a, err := NewAttacker(&storage.FakeStorage{}, "http://host.xz", &Options{
+				Attacker: &fakeBackedAttacker{
+					results: []*vegeta.Result{{Code: 200}},
+				},
+				Exporter:    export.NewFileExporter(dir),
+				IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(tt.cause)
+			metricsCh := make(chan *Metrics, 100)
+			require.NoError(t, a.Attack(ctx, metricsCh))
+			close(metricsCh)
+
+			var final *Metrics
+			for m := range metricsCh {
+				final = m
+			}
+			require.NotNil(t, final)
+			assert.True(t, final.Stopped)
+			assert.Nil(t, final.Aborted)
+
+			content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+			require.NoError(t, err)
+			var summary export.Summary
+			require.NoError(t, json.Unmarshal(content, &summary))
+			assert.True(t, summary.Stopped)
+		})
+	}
+}
+
+func TestAttackWithBaseline(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewAttacker(&storage.FakeStorage{}, "http://host.xz", &Options{
+		Attacker: &fakeBackedAttacker{
+			results: []*vegeta.Result{{Code: 200, Latency: 20 * time.Millisecond}},
+		},
+		Exporter:    export.NewFileExporter(dir),
+		IDGenerator: func() string { return "00000000-0000-0000-0000-000000000000" },
+		Baseline: &export.Baseline{
+			Path: "baseline.json",
+			Summary: export.Summary{
+				LatencyMS: export.LatencySummary{P50: 10},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	metricsCh := make(chan *Metrics, 100)
+	require.NoError(t, a.Attack(context.Background(), metricsCh))
+
+	content, err := os.ReadFile(filepath.Join(dir, "summary-00000000-0000-0000-0000-000000000000.json"))
+	require.NoError(t, err)
+	var summary export.Summary
+	require.NoError(t, json.Unmarshal(content, &summary))
+	require.NotNil(t, summary.Comparison)
+	assert.Equal(t, "baseline.json", summary.Comparison.Baseline)
+	assert.Equal(t, export.Delta{Baseline: 10, Current: 20, Diff: 10, DiffPercent: 100}, summary.Comparison.LatencyMS.P50)
+}
+
+func TestSetRate(t *testing.T) {
+	a := &attacker{}
+	assert.Error(t, a.SetRate(100), "no attack is running")
+
+	began := time.Now()
+	a.setPauser(&pauser{})
+	a.setPacer(newPausablePacer(vegeta.Rate{Freq: 10, Per: time.Second}, 0, a.pauser, nil))
+	assert.Error(t, a.SetRate(0))
+	require.NoError(t, a.SetRate(100))
+	assert.Equal(t, 100.0, a.TargetRate())
+
+	events := a.takeEvents(began)
+	require.Len(t, events, 1)
+	assert.Equal(t, export.EventTypeRateChange, events[0].Type)
+	assert.Equal(t, 100, events[0].Rate)
+	assert.GreaterOrEqual(t, events[0].ElapsedSeconds, 0.0)
+	assert.Empty(t, a.takeEvents(began))
+}
+
+func TestReleaseAfterNextAttackStarted(t *testing.T) {
+	a := &attacker{}
+	first := &pauser{}
+	a.setPauser(first)
+	a.setPacer(newPausablePacer(vegeta.Rate{Freq: 10, Per: time.Second}, 0, first, nil))
+
+	// The next attack starts before the first one returns.
+	second := &pauser{}
+	a.setPauser(second)
+	next := newPausablePacer(vegeta.Rate{Freq: 20, Per: time.Second}, 0, second, nil)
+	a.setPacer(next)
+	a.setPacer(newPausablePacer(vegeta.Rate{Freq: 10, Per: time.Second}, 0, first, nil))
+	a.release(first)
+	assert.Same(t, second, a.pauser)
+	assert.Same(t, next, a.pacer)
+
+	a.release(second)
+	assert.Nil(t, a.pauser)
+	assert.Nil(t, a.pacer)
+}
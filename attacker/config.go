@@ -0,0 +1,416 @@
+package attacker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces the secrets in the redacted config.
+const RedactedValue = "<redacted>"
+
+// ErrRedacted means the config still has some redacted values.
+var ErrRedacted = errors.New("redacted from the config")
+
+// Config is the attack configuration in JSON, which is exported along with each run so that the run can be reproduced.
+type Config struct {
+	// Target is empty if Targets or Stages are given.
+	Target      string      `json:"target,omitempty"`
+	Rate        int         `json:"rate"`
+	Duration    duration    `json:"duration"`
+	Timeout     duration    `json:"timeout"`
+	Method      string      `json:"method"`
+	Body        []byte      `json:"body,omitempty"`
+	MaxBody     int64       `json:"max_body"`
+	Header      http.Header `json:"header,omitempty"`
+	Workers     uint64      `json:"workers"`
+	MaxWorkers  uint64      `json:"max_workers"`
+	KeepAlive   bool        `json:"keep_alive"`
+	Connections int         `json:"connections"`
+	HTTP2       bool        `json:"http2"`
+	LocalAddr   string      `json:"local_addr"`
+	// Resolvers are the addresses of the custom DNS resolvers.
+	Resolvers       []string           `json:"resolvers,omitempty"`
+	Buckets         []duration         `json:"buckets,omitempty"`
+	Targets         []configTarget     `json:"targets,omitempty"`
+	TargetSelection string             `json:"target_selection,omitempty"`
+	LoadProfile     *configLoadProfile `json:"load_profile,omitempty"`
+	Stages          []configStage      `json:"stages,omitempty"`
+	Assertions      []string           `json:"assertions,omitempty"`
+	TLS             ConfigTLS          `json:"tls"`
+}
+
+// ConfigTLS is where the certificates are loaded from, since the loaded ones can't be written out.
+type ConfigTLS struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	CACertFile         string `json:"ca_cert_file,omitempty"`
+}
+
+type configTarget struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   []byte      `json:"body,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Weight int         `json:"weight,omitempty"`
+}
+
+type configStage struct {
+	Name            string         `json:"name"`
+	Rate            int            `json:"rate"`
+	Duration        duration       `json:"duration"`
+	Targets         []configTarget `json:"targets,omitempty"`
+	TargetSelection string         `json:"target_selection,omitempty"`
+	Header          http.Header    `json:"header,omitempty"`
+}
+
+type configLoadProfile struct {
+	Type      string   `json:"type"`
+	From      int      `json:"from,omitempty"`
+	To        int      `json:"to,omitempty"`
+	Over      duration `json:"over,omitempty"`
+	Step      int      `json:"step,omitempty"`
+	Every     duration `json:"every,omitempty"`
+	Mean      int      `json:"mean,omitempty"`
+	Amplitude int      `json:"amplitude,omitempty"`
+	Period    duration `json:"period,omitempty"`
+}
+
+// duration is written as a string like "1m30s".
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// NewConfig gives back the config of the given options, except for the certificates which aren't kept in the options.
+func NewConfig(target string, opts *Options) *Config {
+	c := &Config{
+		Target:          target,
+		Rate:            opts.Rate,
+		Duration:        duration(opts.Duration),
+		Timeout:         duration(opts.Timeout),
+		Method:          opts.Method,
+		Body:            opts.Body,
+		MaxBody:         opts.MaxBody,
+		Header:          opts.Header,
+		Workers:         opts.Workers,
+		MaxWorkers:      opts.MaxWorkers,
+		KeepAlive:       opts.KeepAlive,
+		Connections:     opts.Connections,
+		HTTP2:           opts.HTTP2,
+		Resolvers:       opts.Resolvers,
+		Targets:         newConfigTargets(opts.Targets),
+		TargetSelection: opts.TargetSelection,
+		TLS:             ConfigTLS{InsecureSkipVerify: opts.InsecureSkipVerify},
+	}
+	if opts.LocalAddr.IP != nil {
+		c.LocalAddr = opts.LocalAddr.IP.String()
+	}
+	for _, b := range opts.Buckets {
+		c.Buckets = append(c.Buckets, duration(b))
+	}
+	if p := opts.LoadProfile; p.Type != "" && p.Type != LoadProfileConstant {
+		c.LoadProfile = &configLoadProfile{
+			Type:      p.Type,
+			From:      p.From,
+			To:        p.To,
+			Over:      duration(p.Over),
+			Step:      p.Step,
+			Every:     duration(p.Every),
+			Mean:      p.Mean,
+			Amplitude: p.Amplitude,
+			Period:    duration(p.Period),
+		}
+	}
+	for _, s := range opts.Stages {
+		c.Stages = append(c.Stages, configStage{
+			Name:            s.Name,
+			Rate:            s.Rate,
+			Duration:        duration(s.Duration),
+			Targets:         newConfigTargets(s.Targets),
+			TargetSelection: s.TargetSelection,
+			Header:          s.Header,
+		})
+	}
+	for _, a := range opts.Assertions {
+		c.Assertions = append(c.Assertions, a.String())
+	}
+	return c
+}
+
+func newConfigTargets(targets []Target) []configTarget {
+	var ts []configTarget
+	for _, t := range targets {
+		ts = append(ts, configTarget{Method: t.Method, URL: t.URL, Body: t.Body, Header: t.Header, Weight: t.Weight})
+	}
+	return ts
+}
+
+// ReadConfig reads the config written in JSON out of src.
+func ReadConfig(src io.Reader) (*Config, error) {
+	var c Config
+	if err := json.NewDecoder(src).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Redacted gives back a copy of the config whose credentials are replaced with RedactedValue,
+// which are the values of headers like Authorization, and the passwords and the query parameters like api_key in URLs.
+func (c *Config) Redacted() (*Config, error) {
+	// A round trip makes a deep copy.
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var r Config
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	r.walk(func(h http.Header) {
+		for key, values := range h {
+			if isSecretName(key) {
+				for i := range values {
+					values[i] = RedactedValue
+				}
+			}
+		}
+	}, redactURL)
+	return &r, nil
+}
+
+// SetRedactedHeader sets the given value to the redacted header with the given name wherever it appears.
+// The header is added to the config's Header if it isn't redacted anywhere.
+func (c *Config) SetRedactedHeader(key, value string) {
+	found := false
+	c.walk(func(h http.Header) {
+		for k, values := range h {
+			if !strings.EqualFold(k, key) {
+				continue
+			}
+			for i, v := range values {
+				if v == RedactedValue {
+					values[i] = value
+					found = true
+				}
+			}
+		}
+	}, func(u string) string { return u })
+	if !found {
+		if c.Header == nil {
+			c.Header = make(http.Header)
+		}
+		c.Header[key] = append(c.Header[key], value)
+	}
+}
+
+// walk calls fh with every header and replaces every URL with the one fu gives back.
+func (c *Config) walk(fh func(http.Header), fu func(string) string) {
+	walkTargets := func(targets []configTarget) {
+		for i := range targets {
+			fh(targets[i].Header)
+			targets[i].URL = fu(targets[i].URL)
+		}
+	}
+	fh(c.Header)
+	c.Target = fu(c.Target)
+	walkTargets(c.Targets)
+	for i := range c.Stages {
+		fh(c.Stages[i].Header)
+		walkTargets(c.Stages[i].Targets)
+	}
+}
+
+// isSecretName reports whether the header or the query parameter with the given name is likely to hold credentials.
+// Underscores are taken as hyphens so that query parameters like api_key match as well.
+func isSecretName(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+	switch key {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	for _, s := range []string{"token", "secret", "password", "api-key", "apikey"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL replaces the password and the values of the secret query parameters in the given URL with RedactedValue.
+// The query is rewritten in place rather than re-encoded, so that the order of the parameters is kept.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	redacted := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), RedactedValue)
+			redacted = true
+		}
+	}
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, p := range params {
+			key, _, _ := strings.Cut(p, "=")
+			if name, err := url.QueryUnescape(key); err == nil && isSecretName(name) {
+				params[i] = key + "=" + url.QueryEscape(RedactedValue)
+				redacted = true
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	if !redacted {
+		return raw
+	}
+	return u.String()
+}
+
+// Options gives back the options the config describes, loading the certificates from the files.
+// It fails if any redacted value is left, so that the attack isn't run with the placeholders.
+func (c *Config) Options() (*Options, error) {
+	var redacted []string
+	c.walk(func(h http.Header) {
+		for key, values := range h {
+			for _, v := range values {
+				if v == RedactedValue {
+					redacted = append(redacted, fmt.Sprintf("header %q", key))
+				}
+			}
+		}
+	}, func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		if u.User != nil {
+			if p, _ := u.User.Password(); p == RedactedValue {
+				redacted = append(redacted, fmt.Sprintf("the password in %q", raw))
+			}
+		}
+		for key, values := range u.Query() {
+			for _, v := range values {
+				if v == RedactedValue {
+					redacted = append(redacted, fmt.Sprintf("query parameter %q in %q", key, raw))
+				}
+			}
+		}
+		return raw
+	})
+	if len(redacted) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRedacted, strings.Join(redacted, ", "))
+	}
+
+	opts := &Options{
+		Rate:               c.Rate,
+		Duration:           time.Duration(c.Duration),
+		Timeout:            time.Duration(c.Timeout),
+		Method:             c.Method,
+		Body:               c.Body,
+		MaxBody:            c.MaxBody,
+		Header:             c.Header,
+		Workers:            c.Workers,
+		MaxWorkers:         c.MaxWorkers,
+		KeepAlive:          c.KeepAlive,
+		Connections:        c.Connections,
+		HTTP2:              c.HTTP2,
+		Resolvers:          c.Resolvers,
+		Targets:            toTargets(c.Targets),
+		TargetSelection:    c.TargetSelection,
+		InsecureSkipVerify: c.TLS.InsecureSkipVerify,
+	}
+	if opts.Header == nil {
+		opts.Header = make(http.Header)
+	}
+	if c.LocalAddr != "" {
+		ip := net.ParseIP(c.LocalAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("bad local address %q", c.LocalAddr)
+		}
+		opts.LocalAddr = net.IPAddr{IP: ip}
+	}
+	for _, b := range c.Buckets {
+		opts.Buckets = append(opts.Buckets, time.Duration(b))
+	}
+	if p := c.LoadProfile; p != nil {
+		opts.LoadProfile = LoadProfile{
+			Type:      p.Type,
+			From:      p.From,
+			To:        p.To,
+			Over:      time.Duration(p.Over),
+			Step:      p.Step,
+			Every:     time.Duration(p.Every),
+			Mean:      p.Mean,
+			Amplitude: p.Amplitude,
+			Period:    time.Duration(p.Period),
+		}
+	}
+	for _, s := range c.Stages {
+		opts.Stages = append(opts.Stages, Stage{
+			Name:            s.Name,
+			Rate:            s.Rate,
+			Duration:        time.Duration(s.Duration),
+			Targets:         toTargets(s.Targets),
+			TargetSelection: s.TargetSelection,
+			Header:          s.Header,
+		})
+	}
+	for _, raw := range c.Assertions {
+		a, err := ParseAssertion(raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad assertion %q: %w", raw, err)
+		}
+		opts.Assertions = append(opts.Assertions, a)
+	}
+
+	if c.TLS.CertFile != "" && c.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading PEM key pair %w", err)
+		}
+		opts.TLSCertificates = append(opts.TLSCertificates, cert)
+	}
+	if c.TLS.CACertFile != "" {
+		caCert, err := os.ReadFile(c.TLS.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %q: %w", c.TLS.CACertFile, err)
+		}
+		opts.CACertificatePool = x509.NewCertPool()
+		opts.CACertificatePool.AppendCertsFromPEM(caCert)
+	}
+	return opts, nil
+}
+
+func toTargets(ts []configTarget) []Target {
+	var targets []Target
+	for _, t := range ts {
+		targets = append(targets, Target{Method: t.Method, URL: t.URL, Body: t.Body, Header: t.Header, Weight: t.Weight})
+	}
+	return targets
+}
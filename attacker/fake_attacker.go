@@ -11,6 +11,7 @@ type FakeAttacker struct {
 	rate     int
 	duration time.Duration
 	method   string
+	paused   bool
 }
 
 func (f *FakeAttacker) Attack(ctx context.Context, metricsCh chan *Metrics) error {
@@ -29,11 +30,55 @@ func (f *FakeAttacker) Method() string {
 	return f.method
 }
 
+func (f *FakeAttacker) LoadProfile() LoadProfile {
+	return LoadProfile{}
+}
+
+func (f *FakeAttacker) TargetRate() float64 {
+	return float64(f.rate)
+}
+
+func (f *FakeAttacker) Stages() []Stage {
+	return nil
+}
+
+func (f *FakeAttacker) Buckets() []time.Duration {
+	return nil
+}
+
+func (f *FakeAttacker) Checks() []CheckResult {
+	return nil
+}
+
+func (f *FakeAttacker) Pause() {
+	f.paused = true
+}
+
+func (f *FakeAttacker) Resume() {
+	f.paused = false
+}
+
+func (f *FakeAttacker) Paused() bool {
+	return f.paused
+}
+
+func (f *FakeAttacker) SetRate(rate int) error {
+	f.rate = rate
+	return nil
+}
+
+func (f *FakeAttacker) InFlight() uint64 {
+	return 0
+}
+
 type fakeBackedAttacker struct {
 	results []*vegeta.Result
+	// names are the names given on each call of Attack.
+	names []string
 }
 
-func (f *fakeBackedAttacker) Attack(vegeta.Targeter, vegeta.Pacer, time.Duration, string) <-chan *vegeta.Result {
+func (f *fakeBackedAttacker) Attack(_ vegeta.Targeter, _ vegeta.Pacer, _ time.Duration, name string) <-chan *vegeta.Result {
+	f.names = append(f.names, name)
 	resultCh := make(chan *vegeta.Result)
 	go func() {
 		defer close(resultCh)
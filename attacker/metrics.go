@@ -1,6 +1,7 @@
 package attacker
 
 import (
+	"sort"
 	"time"
 
 	vegeta "github.com/tsenart/vegeta/v12/lib"
@@ -10,8 +11,8 @@ import (
 type Metrics struct {
 	// Latencies holds computed request latency metrics.
 	Latencies LatencyMetrics `json:"latencies"`
-	// Histogram, only if requested
-	// Histogram *vegeta.Histogram `json:"buckets,omitempty"`
+	// Histogram holds the number of responses in each latency bucket, only if the buckets are given.
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
 	// BytesIn holds computed incoming byte metrics.
 	BytesIn ByteMetrics `json:"bytes_in"`
 	// BytesOut holds computed outgoing byte metrics.
@@ -26,6 +27,8 @@ type Metrics struct {
 	Duration time.Duration `json:"duration"`
 	// Wait is the extra time waiting for responses from targets.
 	Wait time.Duration `json:"wait"`
+	// Paused is the time the attack was paused for, which is excluded from Duration.
+	Paused time.Duration `json:"paused,omitempty"`
 	// Requests is the total number of requests executed.
 	Requests uint64 `json:"requests"`
 	// Rate is the rate of sent requests per second.
@@ -38,6 +41,56 @@ type Metrics struct {
 	StatusCodes map[string]int `json:"status_codes"`
 	// Errors is a set of unique errors returned by the targets during the attack.
 	Errors []string `json:"errors"`
+	// Targets holds the metrics of each target, sorted by URL and method.
+	Targets []TargetMetrics `json:"targets,omitempty"`
+	// Stages holds the metrics of each stage which has been started, in order.
+	Stages []StageMetrics `json:"stages,omitempty"`
+	// Checks holds the results of the assertions, only in the final metrics.
+	Checks []CheckResult `json:"checks,omitempty"`
+	// Aborted holds the reason if the attack got aborted by an abort condition.
+	Aborted *AbortError `json:"aborted,omitempty"`
+	// Stopped is true if the attack got stopped by the user or interrupted by a signal before it completed.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// StageMetrics holds computed metrics of requests issued during a single stage.
+type StageMetrics struct {
+	// Name is the name of the stage.
+	Name string `json:"name"`
+	// Rate is the target rate of the stage.
+	Rate int `json:"rate"`
+	// Duration is the duration set to the stage.
+	Duration time.Duration `json:"duration"`
+	// Latencies holds computed request latency metrics.
+	Latencies LatencyMetrics `json:"latencies"`
+	// Earliest is the earliest timestamp in the stage.
+	Earliest time.Time `json:"earliest"`
+	// Latest is the latest timestamp in the stage.
+	Latest time.Time `json:"latest"`
+	// Requests is the total number of requests executed.
+	Requests uint64 `json:"requests"`
+	// Throughput is the rate of successful requests per second.
+	Throughput float64 `json:"throughput"`
+	// Success is the percentage of non-error responses.
+	Success float64 `json:"success"`
+	// StatusCodes is a histogram of the responses' status codes.
+	StatusCodes map[string]int `json:"status_codes"`
+}
+
+// TargetMetrics holds computed metrics of requests to a single target.
+type TargetMetrics struct {
+	// Method is the HTTP method of the target.
+	Method string `json:"method"`
+	// URL is the URL of the target.
+	URL string `json:"url"`
+	// Latencies holds computed request latency metrics.
+	Latencies LatencyMetrics `json:"latencies"`
+	// Requests is the total number of requests executed.
+	Requests uint64 `json:"requests"`
+	// Success is the percentage of non-error responses.
+	Success float64 `json:"success"`
+	// StatusCodes is a histogram of the responses' status codes.
+	StatusCodes map[string]int `json:"status_codes"`
 }
 
 // LatencyMetrics holds computed request latency metrics.
@@ -60,6 +113,16 @@ type LatencyMetrics struct {
 	Min time.Duration `json:"min"`
 }
 
+// HistogramBucket holds the number of responses whose latency is within the range.
+type HistogramBucket struct {
+	// Min is the inclusive lower bound of the range.
+	Min time.Duration `json:"min"`
+	// Max is the exclusive upper bound of the range, zero for the last bucket which has no upper bound.
+	Max time.Duration `json:"max,omitempty"`
+	// Count is the number of responses within the range.
+	Count uint64 `json:"count"`
+}
+
 // ByteMetrics holds computed byte flow metrics.
 type ByteMetrics struct {
 	// Total is the total number of flowing bytes in an attack.
@@ -69,23 +132,9 @@ type ByteMetrics struct {
 }
 
 func newMetrics(m *vegeta.Metrics) *Metrics {
-	statusCodes := make(map[string]int, len(m.StatusCodes))
-	for k, v := range m.StatusCodes {
-		statusCodes[k] = v
-	}
-
 	return &Metrics{
-		Latencies: LatencyMetrics{
-			Total: m.Latencies.Total,
-			Mean:  m.Latencies.Mean,
-			P50:   m.Latencies.Quantile(0.50),
-			P90:   m.Latencies.Quantile(0.90),
-			P95:   m.Latencies.Quantile(0.95),
-			P99:   m.Latencies.Quantile(0.99),
-			Max:   m.Latencies.Max,
-			Min:   m.Latencies.Min,
-		},
-		//Histogram:   m.Histogram,
+		Latencies: newLatencyMetrics(&m.Latencies),
+		Histogram: newHistogram(m.Histogram),
 		BytesIn: ByteMetrics{
 			Total: m.BytesIn.Total,
 			Mean:  m.BytesIn.Mean,
@@ -103,7 +152,110 @@ func newMetrics(m *vegeta.Metrics) *Metrics {
 		Rate:        m.Rate,
 		Throughput:  m.Throughput,
 		Success:     m.Success,
-		StatusCodes: statusCodes,
+		StatusCodes: copyStatusCodes(m.StatusCodes),
 		Errors:      m.Errors,
 	}
 }
+
+// excludePause excludes the given paused time from the duration, and then recomputes the rates.
+func (m *Metrics) excludePause(paused time.Duration) {
+	if paused <= 0 || paused >= m.Duration {
+		return
+	}
+	m.Paused = paused
+	m.Duration -= paused
+	m.Rate = float64(m.Requests) / m.Duration.Seconds()
+	m.Throughput = m.Success * float64(m.Requests) / (m.Duration + m.Wait).Seconds()
+}
+
+func newLatencyMetrics(l *vegeta.LatencyMetrics) LatencyMetrics {
+	return LatencyMetrics{
+		Total: l.Total,
+		Mean:  l.Mean,
+		P50:   l.Quantile(0.50),
+		P90:   l.Quantile(0.90),
+		P95:   l.Quantile(0.95),
+		P99:   l.Quantile(0.99),
+		Max:   l.Max,
+		Min:   l.Min,
+	}
+}
+
+// newHistogram gives back the counts of each bucket, copied so that they don't change while attacking.
+func newHistogram(h *vegeta.Histogram) []HistogramBucket {
+	if h == nil || len(h.Buckets) == 0 {
+		return nil
+	}
+	buckets := make([]HistogramBucket, len(h.Buckets))
+	for i, b := range h.Buckets {
+		buckets[i].Min = b
+		if i+1 < len(h.Buckets) {
+			buckets[i].Max = h.Buckets[i+1]
+		}
+		// The counts are allocated once any result is added.
+		if i < len(h.Counts) {
+			buckets[i].Count = h.Counts[i]
+		}
+	}
+	return buckets
+}
+
+func copyStatusCodes(codes map[string]int) map[string]int {
+	statusCodes := make(map[string]int, len(codes))
+	for k, v := range codes {
+		statusCodes[k] = v
+	}
+	return statusCodes
+}
+
+// targetKey identifies a target.
+type targetKey struct {
+	method string
+	url    string
+}
+
+// newTargetMetrics gives back the metrics of each target, sorted by URL and method.
+func newTargetMetrics(metrics map[targetKey]*vegeta.Metrics) []TargetMetrics {
+	targets := make([]TargetMetrics, 0, len(metrics))
+	for k, m := range metrics {
+		targets = append(targets, TargetMetrics{
+			Method:      k.method,
+			URL:         k.url,
+			Latencies:   newLatencyMetrics(&m.Latencies),
+			Requests:    m.Requests,
+			Success:     m.Success,
+			StatusCodes: copyStatusCodes(m.StatusCodes),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].URL != targets[j].URL {
+			return targets[i].URL < targets[j].URL
+		}
+		return targets[i].Method < targets[j].Method
+	})
+	return targets
+}
+
+// newStageMetrics gives back the metrics of the stages which have been started.
+// metrics are the ones of the stages in order, which can be shorter than stages.
+func newStageMetrics(stages []Stage, metrics []*vegeta.Metrics) []StageMetrics {
+	if len(metrics) == 0 {
+		return nil
+	}
+	res := make([]StageMetrics, 0, len(metrics))
+	for i, m := range metrics {
+		res = append(res, StageMetrics{
+			Name:        stages[i].Name,
+			Rate:        stages[i].Rate,
+			Duration:    stages[i].Duration,
+			Latencies:   newLatencyMetrics(&m.Latencies),
+			Earliest:    m.Earliest,
+			Latest:      m.Latest,
+			Requests:    m.Requests,
+			Throughput:  m.Throughput,
+			Success:     m.Success,
+			StatusCodes: copyStatusCodes(m.StatusCodes),
+		})
+	}
+	return res
+}
@@ -0,0 +1,211 @@
+package attacker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const (
+	// LoadProfileConstant keeps sending requests at Options.Rate.
+	LoadProfileConstant = "constant"
+	// LoadProfileLinear linearly ramps the rate from From up to To over Over, then holds To.
+	LoadProfileLinear = "linear"
+	// LoadProfileStep starts at From and increases the rate by Step every Every, up to To.
+	LoadProfileStep = "step"
+	// LoadProfileSine makes the rate oscillate around Mean with Amplitude, taking Period for a cycle.
+	LoadProfileSine = "sine"
+
+	// pacerPollInterval is how long to wait while the target rate is zero.
+	pacerPollInterval = 10 * time.Millisecond
+)
+
+// LoadProfile describes how the request rate changes over the course of an attack.
+// Which fields are used depends on its Type.
+type LoadProfile struct {
+	// Type is one of "constant", "linear", "step" and "sine". Empty means "constant".
+	Type string
+
+	// From is the rate at the beginning, used by "linear" and "step".
+	From int
+	// To is the rate to reach, used by "linear" and "step". Zero means no upper limit for "step".
+	To int
+	// Over is how long to take to ramp up, used by "linear". Zero means the attack duration.
+	Over time.Duration
+	// Step is the amount of rate increased at a time, used by "step".
+	Step int
+	// Every is how often to increase the rate, used by "step".
+	Every time.Duration
+	// Mean is the rate at the middle of the wave, used by "sine".
+	Mean int
+	// Amplitude is the distance between Mean and the peak, used by "sine".
+	Amplitude int
+	// Period is how long a wave takes, used by "sine".
+	Period time.Duration
+}
+
+// String gives back a human-readable description of the profile.
+func (p LoadProfile) String() string {
+	switch p.Type {
+	case LoadProfileLinear:
+		over := "the duration"
+		if p.Over > 0 {
+			over = p.Over.String()
+		}
+		return fmt.Sprintf("linear %d->%d over %s", p.From, p.To, over)
+	case LoadProfileStep:
+		s := fmt.Sprintf("step %d+%d every %v", p.From, p.Step, p.Every)
+		if p.To > 0 {
+			s += fmt.Sprintf(" up to %d", p.To)
+		}
+		return s
+	case LoadProfileSine:
+		return fmt.Sprintf("sine %d±%d per %v", p.Mean, p.Amplitude, p.Period)
+	default:
+		return LoadProfileConstant
+	}
+}
+
+// pacer gives back the vegeta.Pacer which realizes the profile.
+// rate is used for the "constant" profile, and duration is the duration of the whole attack.
+func (p LoadProfile) pacer(rate int, duration time.Duration) (vegeta.Pacer, error) {
+	switch p.Type {
+	case LoadProfileConstant, "":
+		return vegeta.Rate{Freq: rate, Per: time.Second}, nil
+	case LoadProfileLinear:
+		over := p.Over
+		if over == 0 {
+			over = duration
+		}
+		if over <= 0 {
+			return nil, fmt.Errorf("the ramp-up duration of the linear profile is required for an infinite attack")
+		}
+		if p.From < 0 || p.To < 0 {
+			return nil, fmt.Errorf("rates of the linear profile must be greater than or equal to 0")
+		}
+		return newLinearPacer(p.From, p.To, over), nil
+	case LoadProfileStep:
+		if p.From < 0 || p.Step < 0 || p.To < 0 {
+			return nil, fmt.Errorf("rates of the step profile must be greater than or equal to 0")
+		}
+		if p.Every <= 0 {
+			return nil, fmt.Errorf("the interval of the step profile must be greater than 0s")
+		}
+		return newStepPacer(p.From, p.Step, p.To, p.Every), nil
+	case LoadProfileSine:
+		if p.Period <= 0 {
+			return nil, fmt.Errorf("the period of the sine profile must be greater than 0s")
+		}
+		if p.Mean <= 0 || p.Amplitude < 0 || p.Amplitude > p.Mean {
+			return nil, fmt.Errorf("the sine profile requires 0 < amplitude <= mean")
+		}
+		return vegeta.SinePacer{
+			Period:  p.Period,
+			Mean:    vegeta.Rate{Freq: p.Mean, Per: time.Second},
+			Amp:     vegeta.Rate{Freq: p.Amplitude, Per: time.Second},
+			StartAt: vegeta.MeanUp,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown load profile %q", p.Type)
+	}
+}
+
+// curvePacer paces hits along an arbitrary rate curve.
+// rate gives back the instantaneous hits per second at the given elapsed time,
+// and hits gives back its integral, which is the number of hits expected to be sent until then.
+type curvePacer struct {
+	rate func(elapsed time.Duration) float64
+	hits func(elapsed time.Duration) float64
+}
+
+func (p *curvePacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	expectedHits := p.hits(elapsed)
+	if hits == 0 || hits < uint64(expectedHits) {
+		// Running behind, send next hit immediately.
+		return 0, false
+	}
+
+	rate := p.rate(elapsed)
+	if rate <= 0 {
+		return pacerPollInterval, false
+	}
+	interval := math.Round(1e9 / rate)
+	if n := uint64(interval); n != 0 && math.MaxInt64/n < hits {
+		// We would overflow wait if we continued, so stop the attack.
+		return 0, true
+	}
+	delta := float64(hits+1) - expectedHits
+	return time.Duration(interval * delta), false
+}
+
+func (p *curvePacer) Rate(elapsed time.Duration) float64 {
+	return p.rate(elapsed)
+}
+
+// newLinearPacer gives back a pacer which linearly ramps the rate from "from" to "to" over "over",
+// and then holds "to".
+func newLinearPacer(from, to int, over time.Duration) *curvePacer {
+	slope := float64(to-from) / over.Seconds()
+	return &curvePacer{
+		rate: func(elapsed time.Duration) float64 {
+			if elapsed >= over {
+				return float64(to)
+			}
+			return float64(from) + slope*elapsed.Seconds()
+		},
+		hits: func(elapsed time.Duration) float64 {
+			if elapsed <= 0 {
+				return 0
+			}
+			x := math.Min(elapsed.Seconds(), over.Seconds())
+			h := float64(from)*x + slope*x*x/2
+			if elapsed > over {
+				h += float64(to) * (elapsed - over).Seconds()
+			}
+			return h
+		},
+	}
+}
+
+// newStepPacer gives back a pacer which starts at "from" and increases the rate by "step" every "every".
+// The rate is capped at "to" unless it's zero.
+func newStepPacer(from, step, to int, every time.Duration) *curvePacer {
+	stageRate := func(i int64) float64 {
+		r := float64(from) + float64(step)*float64(i)
+		if to > 0 && r > float64(to) {
+			r = float64(to)
+		}
+		return r
+	}
+	// uncapped is the number of the first steps whose rate isn't capped at "to".
+	uncapped := int64(math.MaxInt64)
+	switch {
+	case to <= 0:
+	case from > to:
+		uncapped = 0
+	case step > 0:
+		uncapped = int64((to-from)/step) + 1
+	}
+	return &curvePacer{
+		rate: func(elapsed time.Duration) float64 {
+			return stageRate(int64(elapsed / every))
+		},
+		hits: func(elapsed time.Duration) float64 {
+			if elapsed <= 0 {
+				return 0
+			}
+			// The full steps are an arithmetic series up to the cap, and then "to" each.
+			stages := int64(elapsed / every)
+			n := stages
+			if n > uncapped {
+				n = uncapped
+			}
+			h := float64(n)*float64(from) + float64(step)*float64(n)*float64(n-1)/2
+			h += float64(stages-n) * float64(to)
+			h *= every.Seconds()
+			return h + stageRate(stages)*(elapsed-time.Duration(stages)*every).Seconds()
+		},
+	}
+}
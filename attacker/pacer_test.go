@@ -0,0 +1,127 @@
+package attacker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadProfilePacer(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  LoadProfile
+		duration time.Duration
+		wantErr  bool
+	}{
+		{
+			name:    "constant",
+			profile: LoadProfile{},
+		},
+		{
+			name:     "linear over the duration",
+			profile:  LoadProfile{Type: LoadProfileLinear, From: 10, To: 100},
+			duration: time.Minute,
+		},
+		{
+			name:    "linear for an infinite attack",
+			profile: LoadProfile{Type: LoadProfileLinear, From: 10, To: 100},
+			wantErr: true,
+		},
+		{
+			name:    "negative linear rate",
+			profile: LoadProfile{Type: LoadProfileLinear, From: -1, To: 100, Over: time.Minute},
+			wantErr: true,
+		},
+		{
+			name:    "step",
+			profile: LoadProfile{Type: LoadProfileStep, From: 10, Step: 10, Every: time.Second},
+		},
+		{
+			name:    "step without interval",
+			profile: LoadProfile{Type: LoadProfileStep, From: 10, Step: 10},
+			wantErr: true,
+		},
+		{
+			name:    "sine",
+			profile: LoadProfile{Type: LoadProfileSine, Mean: 100, Amplitude: 50, Period: time.Minute},
+		},
+		{
+			name:    "sine amplitude larger than mean",
+			profile: LoadProfile{Type: LoadProfileSine, Mean: 10, Amplitude: 50, Period: time.Minute},
+			wantErr: true,
+		},
+		{
+			name:    "unknown",
+			profile: LoadProfile{Type: "exponential"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.profile.pacer(50, tt.duration)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestLinearPacer(t *testing.T) {
+	p := newLinearPacer(10, 110, 10*time.Second)
+	assert.Equal(t, 10.0, p.Rate(0))
+	assert.Equal(t, 60.0, p.Rate(5*time.Second))
+	assert.Equal(t, 110.0, p.Rate(time.Minute))
+
+	assert.Equal(t, 0.0, p.hits(0))
+	assert.InDelta(t, 600.0, p.hits(10*time.Second), 1e-9)
+	assert.InDelta(t, 710.0, p.hits(11*time.Second), 1e-9)
+
+	// Behind the schedule.
+	wait, stop := p.Pace(10*time.Second, 100)
+	assert.Equal(t, time.Duration(0), wait)
+	assert.False(t, stop)
+	// Ahead of the schedule.
+	wait, stop = p.Pace(0, 1)
+	assert.Equal(t, 200*time.Millisecond, wait)
+	assert.False(t, stop)
+}
+
+func TestStepPacer(t *testing.T) {
+	p := newStepPacer(10, 20, 40, time.Second)
+	assert.Equal(t, 10.0, p.Rate(500*time.Millisecond))
+	assert.Equal(t, 30.0, p.Rate(time.Second))
+	assert.Equal(t, 40.0, p.Rate(10*time.Second))
+
+	assert.InDelta(t, 10.0, p.hits(time.Second), 1e-9)
+	assert.InDelta(t, 60.0, p.hits(2500*time.Millisecond), 1e-9)
+	// 10 + 30 + 40 * 8, and then half a second at 40.
+	assert.InDelta(t, 380.0, p.hits(10500*time.Millisecond), 1e-9)
+
+	uncapped := newStepPacer(5, 10, 0, time.Second)
+	// 5 + 15 + 25, and then half a second at 35.
+	assert.InDelta(t, 62.5, uncapped.hits(3500*time.Millisecond), 1e-9)
+
+	above := newStepPacer(50, 10, 20, time.Second)
+	assert.InDelta(t, 50.0, above.hits(2500*time.Millisecond), 1e-9)
+
+	zero := newStepPacer(0, 0, 0, time.Second)
+	wait, _ := zero.Pace(time.Second, 1)
+	assert.Equal(t, pacerPollInterval, wait)
+}
+
+func TestLoadProfileString(t *testing.T) {
+	tests := []struct {
+		profile LoadProfile
+		want    string
+	}{
+		{LoadProfile{}, "constant"},
+		{LoadProfile{Type: LoadProfileLinear, From: 10, To: 500, Over: 5 * time.Minute}, "linear 10->500 over 5m0s"},
+		{LoadProfile{Type: LoadProfileLinear, From: 10, To: 500}, "linear 10->500 over the duration"},
+		{LoadProfile{Type: LoadProfileStep, From: 10, Step: 50, Every: 30 * time.Second, To: 500}, "step 10+50 every 30s up to 500"},
+		{LoadProfile{Type: LoadProfileSine, Mean: 100, Amplitude: 50, Period: 10 * time.Minute}, "sine 100±50 per 10m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.profile.String())
+		})
+	}
+}
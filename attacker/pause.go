@@ -0,0 +1,178 @@
+package attacker
+
+import (
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// pauser holds whether the attack is paused, and how long it has been paused in total.
+type pauser struct {
+	mu       sync.Mutex
+	paused   bool
+	pausedAt time.Time
+	total    time.Duration
+	// resumeCh gets closed when resumed.
+	resumeCh chan struct{}
+}
+
+// pause pauses the attack, and reports whether it wasn't paused.
+func (p *pauser) pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return false
+	}
+	p.paused = true
+	p.pausedAt = time.Now()
+	p.resumeCh = make(chan struct{})
+	return true
+}
+
+// resume resumes the attack, and reports whether it was paused.
+func (p *pauser) resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return false
+	}
+	p.paused = false
+	p.total += time.Since(p.pausedAt)
+	close(p.resumeCh)
+	return true
+}
+
+func (p *pauser) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// pausedFor gives back how long it has been paused in total, including the ongoing pause.
+func (p *pauser) pausedFor() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return p.total + time.Since(p.pausedAt)
+	}
+	return p.total
+}
+
+// wait blocks while paused. It gives back false if stopCh gets closed in the meantime.
+func (p *pauser) wait(stopCh <-chan struct{}) bool {
+	p.mu.Lock()
+	paused, resumeCh := p.paused, p.resumeCh
+	p.mu.Unlock()
+	if !paused {
+		return true
+	}
+	select {
+	case <-resumeCh:
+		return true
+	case <-stopCh:
+		return false
+	}
+}
+
+// pausablePacer stops pacing while paused, and shifts the elapsed time given to the underlying pacer
+// by the time paused, so that the pauses are invisible to it.
+// It takes care of the duration of the attack instead of vegeta, which doesn't know the pauses.
+// Also, the underlying pacer can be replaced with a constant one on the fly.
+type pausablePacer struct {
+	duration time.Duration
+	pauser   *pauser
+	// offset is the time paused before the pacer got started.
+	offset time.Duration
+	began  time.Time
+	stopCh <-chan struct{}
+
+	mu    sync.Mutex
+	pacer vegeta.Pacer
+	// rebasedAt and rebasedHits are the active elapsed time and the hits when the pacer got replaced,
+	// which are subtracted from the ones given to the new pacer.
+	rebasedAt   time.Duration
+	rebasedHits uint64
+	hits        uint64
+	// completed is the number of results of the hits.
+	completed uint64
+}
+
+func newPausablePacer(pacer vegeta.Pacer, duration time.Duration, pauser *pauser, stopCh <-chan struct{}) *pausablePacer {
+	return &pausablePacer{
+		pacer:    pacer,
+		duration: duration,
+		pauser:   pauser,
+		offset:   pauser.pausedFor(),
+		began:    time.Now(),
+		stopCh:   stopCh,
+	}
+}
+
+func (p *pausablePacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	for p.pauser.isPaused() {
+		waitedFrom := time.Now()
+		if !p.pauser.wait(p.stopCh) {
+			return 0, true
+		}
+		// The given elapsed time is stale after the pause, so bring it up to date.
+		// The pause is subtracted from it below, so the wait is computed as if it never happened.
+		elapsed += time.Since(waitedFrom)
+	}
+	active := p.active(elapsed)
+	if p.duration > 0 && active > p.duration {
+		return 0, true
+	}
+	p.mu.Lock()
+	p.hits = hits
+	pacer, at, base := p.pacer, p.rebasedAt, p.rebasedHits
+	p.mu.Unlock()
+	if active < at {
+		active = at
+	}
+	return pacer.Pace(active-at, hits-base)
+}
+
+func (p *pausablePacer) Rate(elapsed time.Duration) float64 {
+	p.mu.Lock()
+	pacer, at := p.pacer, p.rebasedAt
+	p.mu.Unlock()
+	return pacer.Rate(p.active(elapsed) - at)
+}
+
+// currentRate gives back the rate per second it's aiming at right now.
+func (p *pausablePacer) currentRate() float64 {
+	return p.Rate(time.Since(p.began))
+}
+
+// setRate replaces the underlying pacer with the constant one at the given rate, from now on.
+func (p *pausablePacer) setRate(rate int) {
+	at := p.active(time.Since(p.began))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pacer = vegeta.Rate{Freq: rate, Per: time.Second}
+	p.rebasedAt = at
+	p.rebasedHits = p.hits
+}
+
+// complete counts a result of the requests issued through it.
+func (p *pausablePacer) complete() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+}
+
+// inFlight gives back the number of requests issued through it but not completed yet.
+func (p *pausablePacer) inFlight() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.completed > p.hits {
+		return 0
+	}
+	return p.hits - p.completed
+}
+
+// active gives back the elapsed time excluding the pauses.
+func (p *pausablePacer) active(elapsed time.Duration) time.Duration {
+	return elapsed - (p.pauser.pausedFor() - p.offset)
+}
@@ -0,0 +1,117 @@
+package attacker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestPauser(t *testing.T) {
+	p := &pauser{}
+	assert.False(t, p.resume())
+	assert.True(t, p.pause())
+	assert.False(t, p.pause())
+	assert.True(t, p.isPaused())
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, p.resume())
+	assert.False(t, p.isPaused())
+	assert.GreaterOrEqual(t, p.pausedFor(), 10*time.Millisecond)
+}
+
+func TestPausablePacer(t *testing.T) {
+	p := &pauser{total: time.Second}
+	stopCh := make(chan struct{})
+	pacer := newPausablePacer(vegeta.Rate{Freq: 1, Per: time.Second}, 10*time.Second, p, stopCh)
+
+	// Pauses before the pacer started aren't taken into account.
+	assert.Equal(t, 3*time.Second, pacer.active(3*time.Second))
+	wait, stop := pacer.Pace(11*time.Second, 0)
+	assert.Equal(t, time.Duration(0), wait)
+	assert.True(t, stop, "should stop once the duration passed")
+
+	// The time paused after the pacer started gets excluded.
+	p.total += 2 * time.Second
+	assert.Equal(t, 9*time.Second, pacer.active(11*time.Second))
+	_, stop = pacer.Pace(11*time.Second, 9)
+	assert.False(t, stop)
+
+	// Blocks while paused.
+	p.pause()
+	doneCh := make(chan bool)
+	go func() {
+		_, stop := pacer.Pace(time.Second, 0)
+		doneCh <- stop
+	}()
+	select {
+	case <-doneCh:
+		t.Fatal("should be blocked while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+	p.resume()
+	assert.False(t, <-doneCh)
+
+	// Stops if the stop channel gets closed while paused.
+	p.pause()
+	close(stopCh)
+	_, stop = pacer.Pace(time.Second, 0)
+	assert.True(t, stop)
+}
+
+func TestPausablePacerAfterResume(t *testing.T) {
+	p := &pauser{}
+	pacer := newPausablePacer(vegeta.Rate{Freq: 10, Per: time.Second}, 0, p, nil)
+
+	p.pause()
+	waitCh := make(chan time.Duration)
+	go func() {
+		wait, _ := pacer.Pace(100*time.Millisecond, 1)
+		waitCh <- wait
+	}()
+	time.Sleep(20 * time.Millisecond)
+	p.resume()
+	// The next hit is still paced rather than sent right after resuming.
+	assert.InDelta(t, float64(100*time.Millisecond), float64(<-waitCh), float64(5*time.Millisecond))
+}
+
+func TestMetricsExcludePause(t *testing.T) {
+	m := &Metrics{
+		Duration: 10 * time.Second,
+		Wait:     0,
+		Requests: 100,
+		Success:  0.5,
+		Rate:     10,
+	}
+	m.excludePause(5 * time.Second)
+	assert.Equal(t, 5*time.Second, m.Duration)
+	assert.Equal(t, 5*time.Second, m.Paused)
+	assert.Equal(t, 20.0, m.Rate)
+	assert.Equal(t, 10.0, m.Throughput)
+}
+
+func TestPausablePacerSetRate(t *testing.T) {
+	pacer := newPausablePacer(newLinearPacer(10, 100, time.Minute), 0, &pauser{}, nil)
+	assert.InDelta(t, 10.0, pacer.currentRate(), 1)
+
+	pacer.Pace(0, 50)
+	pacer.setRate(200)
+	assert.Equal(t, 200.0, pacer.currentRate())
+	// The hits before the change are invisible to the new pacer.
+	wait, stop := pacer.Pace(pacer.rebasedAt, 50)
+	assert.Equal(t, 5*time.Millisecond, wait)
+	assert.False(t, stop)
+	wait, _ = pacer.Pace(pacer.rebasedAt, 51)
+	assert.Equal(t, 10*time.Millisecond, wait)
+}
+
+func TestPausablePacerInFlight(t *testing.T) {
+	pacer := newPausablePacer(vegeta.Rate{Freq: 10, Per: time.Second}, 0, &pauser{}, nil)
+	assert.Equal(t, uint64(0), pacer.inFlight())
+
+	pacer.Pace(time.Second, 3)
+	assert.Equal(t, uint64(3), pacer.inFlight())
+	pacer.complete()
+	pacer.complete()
+	assert.Equal(t, uint64(1), pacer.inFlight())
+}
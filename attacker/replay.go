@@ -0,0 +1,80 @@
+package attacker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/storage"
+)
+
+// InsertResults inserts the results of an exported run into the storage with their original timestamps,
+// along with the percentiles as of each result, in the same way as attacking.
+// The results have to be sorted by timestamp.
+func InsertResults(s storage.Writer, results []export.Result) error {
+	metrics := &vegeta.Metrics{}
+	for i := range results {
+		res := vegetaResultOf(&results[i])
+		metrics.Add(res)
+		metrics.Close()
+		m := newLatencyMetrics(&metrics.Latencies)
+		err := s.Insert(&storage.Result{
+			Code:      res.Code,
+			Timestamp: res.Timestamp,
+			Latency:   res.Latency,
+			P50:       m.P50,
+			P90:       m.P90,
+			P95:       m.P95,
+			P99:       m.P99,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert the result at %s: %w", res.Timestamp, err)
+		}
+	}
+	return nil
+}
+
+// ResultsMetrics computes the metrics out of the results of an exported run.
+func ResultsMetrics(results []export.Result) *Metrics {
+	metrics := &vegeta.Metrics{}
+	targetMetrics := make(map[targetKey]*vegeta.Metrics)
+	for i := range results {
+		res := vegetaResultOf(&results[i])
+		metrics.Add(res)
+		key := targetKey{method: res.Method, url: res.URL}
+		tm, ok := targetMetrics[key]
+		if !ok {
+			tm = &vegeta.Metrics{}
+			targetMetrics[key] = tm
+		}
+		tm.Add(res)
+	}
+	metrics.Close()
+	for _, tm := range targetMetrics {
+		tm.Close()
+	}
+	m := newMetrics(metrics)
+	m.Targets = newTargetMetrics(targetMetrics)
+	return m
+}
+
+func vegetaResultOf(r *export.Result) *vegeta.Result {
+	var latency time.Duration
+	// Latencies which weren't finite numbers are exported as empty.
+	if !math.IsNaN(r.LatencyNS) && !math.IsInf(r.LatencyNS, 0) {
+		latency = time.Duration(r.LatencyNS)
+	}
+	return &vegeta.Result{
+		Code:      r.StatusCode,
+		Timestamp: r.Timestamp,
+		Latency:   latency,
+		URL:       r.URL,
+		Method:    r.Method,
+		BytesIn:   r.BytesIn,
+		BytesOut:  r.BytesOut,
+		Error:     r.Error,
+	}
+}
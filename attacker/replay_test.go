@@ -0,0 +1,57 @@
+package attacker
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/storage"
+)
+
+type recordingStorage struct {
+	results []*storage.Result
+}
+
+func (r *recordingStorage) Insert(result *storage.Result) error {
+	r.results = append(r.results, result)
+	return nil
+}
+
+func TestInsertResults(t *testing.T) {
+	began := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	results := []export.Result{
+		{Timestamp: began, LatencyNS: 1e6, StatusCode: 200},
+		{Timestamp: began.Add(time.Second), LatencyNS: math.NaN(), StatusCode: 0},
+		{Timestamp: began.Add(2 * time.Second), LatencyNS: 3e6, StatusCode: 500},
+	}
+	s := &recordingStorage{}
+	require.NoError(t, InsertResults(s, results))
+	require.Len(t, s.results, 3)
+	assert.Equal(t, began, s.results[0].Timestamp)
+	assert.Equal(t, time.Millisecond, s.results[0].Latency)
+	assert.Equal(t, time.Millisecond, s.results[0].P99)
+	assert.Equal(t, time.Duration(0), s.results[1].Latency)
+	assert.Equal(t, uint16(500), s.results[2].Code)
+	assert.Equal(t, 3*time.Millisecond, s.results[2].P99)
+}
+
+func TestResultsMetrics(t *testing.T) {
+	began := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	m := ResultsMetrics([]export.Result{
+		{Timestamp: began, LatencyNS: 1e6, URL: "http://host.xz/a", Method: "GET", StatusCode: 200},
+		{Timestamp: began.Add(time.Second), LatencyNS: 3e6, URL: "http://host.xz/b", Method: "GET", StatusCode: 500},
+	})
+	assert.Equal(t, uint64(2), m.Requests)
+	assert.Equal(t, 0.5, m.Success)
+	assert.Equal(t, map[string]int{"200": 1, "500": 1}, m.StatusCodes)
+	assert.Equal(t, 3*time.Millisecond, m.Latencies.Max)
+	require.Len(t, m.Targets, 2)
+	assert.Equal(t, "http://host.xz/a", m.Targets[0].URL)
+
+	empty := ResultsMetrics(nil)
+	assert.Equal(t, uint64(0), empty.Requests)
+}
@@ -0,0 +1,169 @@
+package attacker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Stage is a phase of a multi-stage attack. Stages are run back-to-back.
+type Stage struct {
+	Name     string
+	Rate     int
+	Duration time.Duration
+	// Targets are used instead of the attacker's ones during the stage if given.
+	Targets []Target
+	// TargetSelection is how to pick one of Targets for each request; "round-robin" or "weighted".
+	TargetSelection string
+	// Header is added to every request issued during the stage.
+	Header http.Header
+}
+
+// scenarioFile is the schema of scenario files, which looks like:
+//
+//	stages:
+//	  - name: warm-up
+//	    rate: 20
+//	    duration: 30s
+//	  - name: spike
+//	    rate: 1000
+//	    duration: 10s
+//	    headers:
+//	      X-Stage: spike
+//	    targets:
+//	      - url: https://foo.bar/a
+//	        method: POST
+//	        body: '{"foo": 1}'
+//
+// JSON is also accepted as it's a subset of YAML.
+type scenarioFile struct {
+	Stages []scenarioStage `yaml:"stages"`
+}
+
+type scenarioStage struct {
+	Name      string            `yaml:"name"`
+	Rate      int               `yaml:"rate"`
+	Duration  string            `yaml:"duration"`
+	Targets   []scenarioTarget  `yaml:"targets"`
+	Selection string            `yaml:"selection"`
+	Headers   map[string]string `yaml:"headers"`
+}
+
+type scenarioTarget struct {
+	Method string            `yaml:"method"`
+	URL    string            `yaml:"url"`
+	Body   string            `yaml:"body"`
+	Header map[string]string `yaml:"header"`
+	Weight *int              `yaml:"weight"`
+}
+
+// ReadScenario reads the stages written in a YAML or JSON scenario file out of src.
+// The given method, body and header are used as the defaults of each target.
+func ReadScenario(src io.Reader, method string, body []byte, header http.Header) ([]Stage, error) {
+	var sf scenarioFile
+	dec := yaml.NewDecoder(src)
+	dec.KnownFields(true)
+	if err := dec.Decode(&sf); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("bad scenario: %w", err)
+	}
+	if len(sf.Stages) == 0 {
+		return nil, errors.New("bad scenario: no stages given")
+	}
+
+	stages := make([]Stage, 0, len(sf.Stages))
+	for i, ss := range sf.Stages {
+		stage := Stage{
+			Name:            ss.Name,
+			Rate:            ss.Rate,
+			TargetSelection: ss.Selection,
+			Header:          http.Header{},
+		}
+		if stage.Name == "" {
+			stage.Name = fmt.Sprintf("stage-%d", i+1)
+		}
+		if stage.Rate < 0 {
+			return nil, fmt.Errorf("bad stage %q: rate must be greater than or equal to 0", stage.Name)
+		}
+		d, err := time.ParseDuration(ss.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("bad stage %q: wrong duration: %w", stage.Name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("bad stage %q: duration must be greater than 0s", stage.Name)
+		}
+		stage.Duration = d
+		for k, v := range ss.Headers {
+			stage.Header[k] = []string{v}
+		}
+		for _, st := range ss.Targets {
+			t, err := st.toTarget(method, body, header)
+			if err != nil {
+				return nil, fmt.Errorf("bad stage %q: %w", stage.Name, err)
+			}
+			stage.Targets = append(stage.Targets, t)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func (st *scenarioTarget) toTarget(method string, body []byte, header http.Header) (Target, error) {
+	if _, err := url.ParseRequestURI(st.URL); err != nil {
+		return Target{}, fmt.Errorf("bad URL: %s", st.URL)
+	}
+	t := Target{
+		Method: st.Method,
+		URL:    st.URL,
+		Body:   body,
+		Header: http.Header{},
+		Weight: 1,
+	}
+	if t.Method == "" {
+		t.Method = method
+	}
+	if st.Body != "" {
+		t.Body = []byte(st.Body)
+	}
+	for k, vs := range header {
+		t.Header[k] = append([]string(nil), vs...)
+	}
+	for k, v := range st.Header {
+		t.Header[k] = append(t.Header[k], v)
+	}
+	if st.Weight != nil {
+		if *st.Weight < 0 {
+			return Target{}, fmt.Errorf("weight of %s %s must be greater than or equal to 0", t.Method, t.URL)
+		}
+		t.Weight = *st.Weight
+	}
+	return t, nil
+}
+
+// stageTargets gives back the targets used during the given stage, with the stage headers added.
+func stageTargets(stage Stage, defaults []Target) []Target {
+	base := stage.Targets
+	if len(base) == 0 {
+		base = defaults
+	}
+	if len(stage.Header) == 0 {
+		return base
+	}
+	targets := make([]Target, 0, len(base))
+	for _, t := range base {
+		header := make(http.Header, len(t.Header)+len(stage.Header))
+		for k, vs := range t.Header {
+			header[k] = append([]string(nil), vs...)
+		}
+		for k, vs := range stage.Header {
+			header[k] = append([]string(nil), vs...)
+		}
+		t.Header = header
+		targets = append(targets, t)
+	}
+	return targets
+}
@@ -0,0 +1,109 @@
+package attacker
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []Stage
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			src: `
+stages:
+  - rate: 20
+    duration: 30s
+  - name: spike
+    rate: 1000
+    duration: 10s
+    headers:
+      X-Stage: spike
+    selection: weighted
+    targets:
+      - url: http://host.xz/a
+        weight: 2
+      - url: http://host.xz/b
+        method: POST
+        body: foo
+        header:
+          X-Foo: bar
+`,
+			want: []Stage{
+				{Name: "stage-1", Rate: 20, Duration: 30 * time.Second, Header: http.Header{}},
+				{
+					Name:            "spike",
+					Rate:            1000,
+					Duration:        10 * time.Second,
+					TargetSelection: "weighted",
+					Header:          http.Header{"X-Stage": []string{"spike"}},
+					Targets: []Target{
+						{Method: "GET", URL: "http://host.xz/a", Body: []byte("default"), Header: http.Header{"X-Default": []string{"1"}}, Weight: 2},
+						{Method: "POST", URL: "http://host.xz/b", Body: []byte("foo"), Header: http.Header{"X-Default": []string{"1"}, "X-Foo": []string{"bar"}}, Weight: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "json",
+			src:  `{"stages": [{"name": "hold", "rate": 200, "duration": "5m"}]}`,
+			want: []Stage{
+				{Name: "hold", Rate: 200, Duration: 5 * time.Minute, Header: http.Header{}},
+			},
+		},
+		{
+			name:    "no stages",
+			src:     "stages: []",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			src:     "stages:\n  - rate: 1\n    duration: 1s\n    foo: bar\n",
+			wantErr: true,
+		},
+		{
+			name:    "no duration",
+			src:     "stages:\n  - rate: 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "negative rate",
+			src:     "stages:\n  - rate: -1\n    duration: 1s\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad URL",
+			src:     "stages:\n  - rate: 1\n    duration: 1s\n    targets:\n      - url: host\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadScenario(strings.NewReader(tt.src), "GET", []byte("default"), http.Header{"X-Default": []string{"1"}})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStageTargets(t *testing.T) {
+	defaults := []Target{{Method: "GET", URL: "http://host.xz", Header: http.Header{"X-Foo": []string{"1"}}}}
+
+	got := stageTargets(Stage{Header: http.Header{"X-Foo": []string{"2"}, "X-Bar": []string{"3"}}}, defaults)
+	assert.Equal(t, []Target{
+		{Method: "GET", URL: "http://host.xz", Header: http.Header{"X-Foo": []string{"2"}, "X-Bar": []string{"3"}}},
+	}, got)
+	// The defaults must be left as is.
+	assert.Equal(t, http.Header{"X-Foo": []string{"1"}}, defaults[0].Header)
+
+	own := []Target{{Method: "POST", URL: "http://host.xz/a"}}
+	assert.Equal(t, own, stageTargets(Stage{Targets: own}, defaults))
+}
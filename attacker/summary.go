@@ -25,16 +25,7 @@ func newSummary(targetURL, method string, rate int, duration time.Duration, metr
 			SuccessRatio: metrics.Success,
 		},
 		Throughput: metrics.Throughput,
-		LatencyMS: export.LatencySummary{
-			Total: durationToMillis(metrics.Latencies.Total),
-			Mean:  durationToMillis(metrics.Latencies.Mean),
-			P50:   durationToMillis(metrics.Latencies.P50),
-			P90:   durationToMillis(metrics.Latencies.P90),
-			P95:   durationToMillis(metrics.Latencies.P95),
-			P99:   durationToMillis(metrics.Latencies.P99),
-			Max:   durationToMillis(metrics.Latencies.Max),
-			Min:   durationToMillis(metrics.Latencies.Min),
-		},
+		LatencyMS:  newLatencySummary(metrics.Latencies),
 		Bytes: export.BytesSummary{
 			In: export.BytesFlowSummary{
 				Total: metrics.BytesIn.Total,
@@ -46,7 +37,103 @@ func newSummary(targetURL, method string, rate int, duration time.Duration, metr
 			},
 		},
 		StatusCodes: export.StatusCodesSummary(metrics.StatusCodes),
+		Histogram:   newHistogramSummary(metrics.Histogram),
+		Targets:     newTargetBreakdownSummaries(metrics.Targets),
+		Stages:      newStageSummaries(metrics.Stages),
+		Checks:      newCheckSummaries(metrics.Checks),
+		Errors:      metrics.Errors,
+	}
+}
+
+func newLatencySummary(l LatencyMetrics) export.LatencySummary {
+	return export.LatencySummary{
+		Total: durationToMillis(l.Total),
+		Mean:  durationToMillis(l.Mean),
+		P50:   durationToMillis(l.P50),
+		P90:   durationToMillis(l.P90),
+		P95:   durationToMillis(l.P95),
+		P99:   durationToMillis(l.P99),
+		Max:   durationToMillis(l.Max),
+		Min:   durationToMillis(l.Min),
+	}
+}
+
+func newHistogramSummary(buckets []HistogramBucket) []export.HistogramBucketSummary {
+	if len(buckets) == 0 {
+		return nil
+	}
+	summaries := make([]export.HistogramBucketSummary, 0, len(buckets))
+	for _, b := range buckets {
+		summaries = append(summaries, export.HistogramBucketSummary{
+			MinMS: durationToMillis(b.Min),
+			MaxMS: durationToMillis(b.Max),
+			Count: b.Count,
+		})
+	}
+	return summaries
+}
+
+// newTargetBreakdownSummaries gives back nil unless there are multiple targets
+// because the breakdown of a single target is identical to the overall summary.
+func newTargetBreakdownSummaries(targets []TargetMetrics) []export.TargetBreakdownSummary {
+	if len(targets) < 2 {
+		return nil
+	}
+	summaries := make([]export.TargetBreakdownSummary, 0, len(targets))
+	for _, t := range targets {
+		summaries = append(summaries, export.TargetBreakdownSummary{
+			URL:    t.URL,
+			Method: t.Method,
+			Requests: export.RequestsSummary{
+				Count:        t.Requests,
+				SuccessRatio: t.Success,
+			},
+			LatencyMS:   newLatencySummary(t.Latencies),
+			StatusCodes: export.StatusCodesSummary(t.StatusCodes),
+		})
+	}
+	return summaries
+}
+
+func newStageSummaries(stages []StageMetrics) []export.StageSummary {
+	if len(stages) == 0 {
+		return nil
+	}
+	summaries := make([]export.StageSummary, 0, len(stages))
+	for _, s := range stages {
+		summaries = append(summaries, export.StageSummary{
+			Name:            s.Name,
+			Rate:            s.Rate,
+			DurationSeconds: s.Duration.Seconds(),
+			Timing: export.TimingSummary{
+				Earliest: s.Earliest,
+				Latest:   s.Latest,
+			},
+			Requests: export.RequestsSummary{
+				Count:        s.Requests,
+				SuccessRatio: s.Success,
+			},
+			Throughput:  s.Throughput,
+			LatencyMS:   newLatencySummary(s.Latencies),
+			StatusCodes: export.StatusCodesSummary(s.StatusCodes),
+		})
+	}
+	return summaries
+}
+
+func newCheckSummaries(checks []CheckResult) []export.CheckSummary {
+	if len(checks) == 0 {
+		return nil
+	}
+	summaries := make([]export.CheckSummary, 0, len(checks))
+	for _, c := range checks {
+		summaries = append(summaries, export.CheckSummary{
+			Assertion: c.Assertion,
+			Actual:    c.Actual,
+			Passed:    c.Passed,
+		})
 	}
+	return summaries
 }
 
 func durationToMillis(d time.Duration) float64 {
@@ -0,0 +1,188 @@
+package attacker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+const (
+	// TargetsFormatHTTP is the vegeta's HTTP format, which looks like:
+	//
+	//   GET https://foo.bar/a
+	//   Header-X: 123
+	//   @/path/to/body/file
+	TargetsFormatHTTP = "http"
+	// TargetsFormatJSON is the vegeta's JSON format, where each line is an object like:
+	//
+	//   {"method": "GET", "url": "https://foo.bar/a", "header": {"Header-X": ["123"]}, "body": "base64 encoded", "weight": 2}
+	//
+	// "weight" is an extension by ali, which is used for the weighted selection.
+	TargetsFormatJSON = "json"
+
+	// SelectionRoundRobin picks the targets in turn.
+	SelectionRoundRobin = "round-robin"
+	// SelectionWeighted picks the targets randomly, in proportion to their weights.
+	SelectionWeighted = "weighted"
+)
+
+// Target describes a request to be issued.
+type Target struct {
+	Method string
+	URL    string
+	Body   []byte
+	Header http.Header
+	// Weight is the relative frequency of the target, only used with the weighted selection.
+	Weight int
+}
+
+// ReadTargets reads all targets written in the given format out of src.
+// The given body and header are used as the defaults of each target.
+func ReadTargets(src io.Reader, format string, body []byte, header http.Header) ([]Target, error) {
+	switch format {
+	case TargetsFormatHTTP, "":
+		return readHTTPTargets(src, body, header)
+	case TargetsFormatJSON:
+		return readJSONTargets(src, body, header)
+	default:
+		return nil, fmt.Errorf("unknown targets format %q", format)
+	}
+}
+
+func readHTTPTargets(src io.Reader, body []byte, header http.Header) ([]Target, error) {
+	vts, err := vegeta.ReadAllTargets(vegeta.NewHTTPTargeter(src, body, header))
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]Target, 0, len(vts))
+	for _, vt := range vts {
+		targets = append(targets, Target{
+			Method: vt.Method,
+			URL:    vt.URL,
+			Body:   vt.Body,
+			Header: vt.Header,
+			Weight: 1,
+		})
+	}
+	return targets, nil
+}
+
+type jsonTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Body   []byte              `json:"body"`
+	Header map[string][]string `json:"header"`
+	Weight *int                `json:"weight"`
+}
+
+func readJSONTargets(src io.Reader, body []byte, header http.Header) ([]Target, error) {
+	var targets []Target
+	dec := json.NewDecoder(src)
+	for {
+		var jt jsonTarget
+		err := dec.Decode(&jt)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad target: %w", err)
+		}
+		if jt.Method == "" {
+			return nil, errors.New("bad target: method is required")
+		}
+		if _, err := url.ParseRequestURI(jt.URL); err != nil {
+			return nil, fmt.Errorf("bad URL: %s", jt.URL)
+		}
+		t := Target{
+			Method: jt.Method,
+			URL:    jt.URL,
+			Body:   body,
+			Header: http.Header{},
+			Weight: 1,
+		}
+		if len(jt.Body) > 0 {
+			t.Body = jt.Body
+		}
+		for k, vs := range header {
+			t.Header[k] = append([]string(nil), vs...)
+		}
+		for k, vs := range jt.Header {
+			t.Header[k] = append(t.Header[k], vs...)
+		}
+		if jt.Weight != nil {
+			if *jt.Weight < 0 {
+				return nil, fmt.Errorf("weight of %s %s must be greater than or equal to 0", jt.Method, jt.URL)
+			}
+			t.Weight = *jt.Weight
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, vegeta.ErrNoTargets
+	}
+	return targets, nil
+}
+
+func toVegetaTargets(targets []Target) []vegeta.Target {
+	vts := make([]vegeta.Target, 0, len(targets))
+	for _, t := range targets {
+		vts = append(vts, vegeta.Target{
+			Method: t.Method,
+			URL:    t.URL,
+			Body:   t.Body,
+			Header: t.Header,
+		})
+	}
+	return vts
+}
+
+func newTargeter(targets []Target, selection string) (vegeta.Targeter, error) {
+	if len(targets) == 0 {
+		return nil, vegeta.ErrNoTargets
+	}
+	switch selection {
+	case SelectionRoundRobin, "":
+		return vegeta.NewStaticTargeter(toVegetaTargets(targets)...), nil
+	case SelectionWeighted:
+		return newWeightedTargeter(targets, rand.New(rand.NewSource(time.Now().UnixNano())))
+	default:
+		return nil, fmt.Errorf("unknown target selection %q", selection)
+	}
+}
+
+// newWeightedTargeter gives back a Targeter which randomly picks one of the given targets,
+// in proportion to their weights.
+func newWeightedTargeter(targets []Target, rnd *rand.Rand) (vegeta.Targeter, error) {
+	vts := toVegetaTargets(targets)
+	cumulative := make([]int, len(targets))
+	total := 0
+	for i, t := range targets {
+		total += t.Weight
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		return nil, errors.New("the sum of target weights must be greater than 0")
+	}
+
+	// *rand.Rand isn't goroutine safe while the targeter gets called by multiple workers.
+	var mu sync.Mutex
+	return func(tgt *vegeta.Target) error {
+		if tgt == nil {
+			return vegeta.ErrNilTarget
+		}
+		mu.Lock()
+		n := rnd.Intn(total)
+		mu.Unlock()
+		*tgt = vts[sort.SearchInts(cumulative, n+1)]
+		return nil
+	}, nil
+}
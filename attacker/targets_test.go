@@ -0,0 +1,121 @@
+package attacker
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestReadTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		format  string
+		body    []byte
+		header  http.Header
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name:   "http format",
+			src:    "GET http://host.xz/a\nX-Foo: bar\n\nPOST http://host.xz/b\n",
+			format: TargetsFormatHTTP,
+			body:   []byte("default"),
+			header: http.Header{},
+			want: []Target{
+				{Method: "GET", URL: "http://host.xz/a", Body: []byte("default"), Header: http.Header{"X-Foo": []string{"bar"}}, Weight: 1},
+				{Method: "POST", URL: "http://host.xz/b", Body: []byte("default"), Header: http.Header{}, Weight: 1},
+			},
+		},
+		{
+			name:   "json format",
+			src:    `{"method": "GET", "url": "http://host.xz/a", "weight": 3}` + "\n" + `{"method": "POST", "url": "http://host.xz/b", "body": "Ym9keQ==", "header": {"X-Foo": ["bar"]}}`,
+			format: TargetsFormatJSON,
+			header: http.Header{"X-Default": []string{"1"}},
+			want: []Target{
+				{Method: "GET", URL: "http://host.xz/a", Header: http.Header{"X-Default": []string{"1"}}, Weight: 3},
+				{Method: "POST", URL: "http://host.xz/b", Body: []byte("body"), Header: http.Header{"X-Default": []string{"1"}, "X-Foo": []string{"bar"}}, Weight: 1},
+			},
+		},
+		{
+			name:    "no targets given",
+			src:     "",
+			format:  TargetsFormatJSON,
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			src:     `{"method": "GET", "url": "http://host.xz/a", "weight": -1}`,
+			format:  TargetsFormatJSON,
+			wantErr: true,
+		},
+		{
+			name:    "bad URL",
+			src:     `{"method": "GET", "url": "host"}`,
+			format:  TargetsFormatJSON,
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			src:     "GET http://host.xz/a",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadTargets(strings.NewReader(tt.src), tt.format, tt.body, tt.header)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewTargeter(t *testing.T) {
+	targets := []Target{
+		{Method: "GET", URL: "http://host.xz/a", Weight: 1},
+		{Method: "GET", URL: "http://host.xz/b", Weight: 1},
+	}
+
+	tr, err := newTargeter(targets, SelectionRoundRobin)
+	require.NoError(t, err)
+	var got []string
+	for i := 0; i < 3; i++ {
+		var tgt vegeta.Target
+		require.NoError(t, tr(&tgt))
+		got = append(got, tgt.URL)
+	}
+	assert.Equal(t, []string{"http://host.xz/a", "http://host.xz/b", "http://host.xz/a"}, got)
+
+	_, err = newTargeter(targets, "unknown")
+	assert.Error(t, err)
+	_, err = newTargeter(nil, SelectionRoundRobin)
+	assert.Error(t, err)
+}
+
+func TestNewWeightedTargeter(t *testing.T) {
+	tr, err := newWeightedTargeter([]Target{
+		{Method: "GET", URL: "http://host.xz/a", Weight: 3},
+		{Method: "GET", URL: "http://host.xz/b", Weight: 1},
+		{Method: "GET", URL: "http://host.xz/c", Weight: 0},
+	}, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		var tgt vegeta.Target
+		require.NoError(t, tr(&tgt))
+		counts[tgt.URL]++
+	}
+	assert.Zero(t, counts["http://host.xz/c"])
+	assert.InDelta(t, 3000, counts["http://host.xz/a"], 200)
+	assert.InDelta(t, 1000, counts["http://host.xz/b"], 200)
+
+	_, err = newWeightedTargeter([]Target{{Method: "GET", URL: "http://host.xz/a", Weight: 0}}, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Baseline is the summary of a prior run, which the current run gets compared against.
+type Baseline struct {
+	// Path is the path to the summary file.
+	Path    string
+	Summary Summary
+}
+
+// ReadBaseline reads the summary written by a prior run from the given path.
+func ReadBaseline(path string) (*Baseline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %q: %w", path, err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode baseline %q: %w", path, err)
+	}
+	if summary.Requests.Count == 0 {
+		return nil, fmt.Errorf("baseline %q has no requests", path)
+	}
+	return &Baseline{Path: path, Summary: summary}, nil
+}
+
+// ComparisonSummary holds the differences between the run and the baseline.
+type ComparisonSummary struct {
+	Baseline     string            `json:"baseline"`
+	LatencyMS    LatencyComparison `json:"latency_ms"`
+	Throughput   Delta             `json:"throughput"`
+	SuccessRatio Delta             `json:"success_ratio"`
+}
+
+type LatencyComparison struct {
+	Mean Delta `json:"mean"`
+	P50  Delta `json:"p50"`
+	P90  Delta `json:"p90"`
+	P95  Delta `json:"p95"`
+	P99  Delta `json:"p99"`
+	Max  Delta `json:"max"`
+	Min  Delta `json:"min"`
+}
+
+// Delta is the difference of a value from the baseline.
+type Delta struct {
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Diff     float64 `json:"diff"`
+	// DiffPercent is the difference relative to the baseline, which is 0 if the baseline is 0.
+	DiffPercent float64 `json:"diff_percent"`
+}
+
+// NewDelta gives back the difference of the current value from the baseline one.
+func NewDelta(baseline, current float64) Delta {
+	d := Delta{
+		Baseline: baseline,
+		Current:  current,
+		Diff:     current - baseline,
+	}
+	if baseline != 0 {
+		d.DiffPercent = d.Diff / baseline * 100
+	}
+	return d
+}
+
+// Compare gives back the differences of the given summary from the baseline.
+func (b *Baseline) Compare(current Summary) *ComparisonSummary {
+	base := b.Summary
+	return &ComparisonSummary{
+		Baseline: b.Path,
+		LatencyMS: LatencyComparison{
+			Mean: NewDelta(base.LatencyMS.Mean, current.LatencyMS.Mean),
+			P50:  NewDelta(base.LatencyMS.P50, current.LatencyMS.P50),
+			P90:  NewDelta(base.LatencyMS.P90, current.LatencyMS.P90),
+			P95:  NewDelta(base.LatencyMS.P95, current.LatencyMS.P95),
+			P99:  NewDelta(base.LatencyMS.P99, current.LatencyMS.P99),
+			Max:  NewDelta(base.LatencyMS.Max, current.LatencyMS.Max),
+			Min:  NewDelta(base.LatencyMS.Min, current.LatencyMS.Min),
+		},
+		Throughput:   NewDelta(base.Throughput, current.Throughput),
+		SuccessRatio: NewDelta(base.Requests.SuccessRatio, current.Requests.SuccessRatio),
+	}
+}
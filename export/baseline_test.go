@@ -0,0 +1,55 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBaseline(t *testing.T) {
+	path := filepath.Join("..", "testdata", "export", "basic", "summary-00000000-0000-0000-0000-000000000000.json")
+	b, err := ReadBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, b.Path)
+	assert.Equal(t, uint64(100), b.Summary.Requests.Count)
+	assert.Equal(t, 935.49, b.Summary.LatencyMS.P99)
+
+	dir := t.TempDir()
+	_, err = ReadBaseline(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	broken := filepath.Join(dir, "broken.json")
+	require.NoError(t, os.WriteFile(broken, []byte("{"), 0o644))
+	_, err = ReadBaseline(broken)
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte("{}"), 0o644))
+	_, err = ReadBaseline(empty)
+	assert.Error(t, err)
+}
+
+func TestBaselineCompare(t *testing.T) {
+	b := &Baseline{
+		Path: "baseline.json",
+		Summary: Summary{
+			Requests:   RequestsSummary{Count: 100, SuccessRatio: 1},
+			Throughput: 50,
+			LatencyMS:  LatencySummary{P50: 10, P99: 100},
+		},
+	}
+	got := b.Compare(Summary{
+		Requests:   RequestsSummary{Count: 100, SuccessRatio: 0.5},
+		Throughput: 25,
+		LatencyMS:  LatencySummary{P50: 12, P99: 50, Min: 1},
+	})
+	assert.Equal(t, "baseline.json", got.Baseline)
+	assert.Equal(t, Delta{Baseline: 10, Current: 12, Diff: 2, DiffPercent: 20}, got.LatencyMS.P50)
+	assert.Equal(t, Delta{Baseline: 100, Current: 50, Diff: -50, DiffPercent: -50}, got.LatencyMS.P99)
+	assert.Equal(t, Delta{Baseline: 0, Current: 1, Diff: 1, DiffPercent: 0}, got.LatencyMS.Min)
+	assert.Equal(t, Delta{Baseline: 50, Current: 25, Diff: -25, DiffPercent: -50}, got.Throughput)
+	assert.Equal(t, Delta{Baseline: 1, Current: 0.5, Diff: -0.5, DiffPercent: -50}, got.SuccessRatio)
+}
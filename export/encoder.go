@@ -0,0 +1,412 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Format is the format of the results file.
+type Format string
+
+const (
+	// FormatCSV writes the results as CSV.
+	FormatCSV Format = "csv"
+	// FormatJSONL writes the results as JSON Lines, one object per request.
+	FormatJSONL Format = "jsonl"
+	// FormatGob writes the results in vegeta's binary format,
+	// which can be read by "vegeta report" and "vegeta plot".
+	FormatGob Format = "gob"
+)
+
+// Formats are all the supported formats, in the order the results file is looked up by FileReader.
+var Formats = []Format{FormatCSV, FormatJSONL, FormatGob}
+
+// ParseFormat gives back the format with the given name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q", s)
+}
+
+func (f Format) resultsFilename() string {
+	return "results." + string(f)
+}
+
+// resultEncoder writes the results in a format.
+type resultEncoder interface {
+	// begin writes what precedes the results, carrying over the results of the existing file if not nil.
+	begin(existing io.Reader) error
+	encode(id string, res Result) error
+	flush() error
+}
+
+func newResultEncoder(w io.Writer, opts FileOptions) resultEncoder {
+	headers := make([]string, 0, len(opts.Headers))
+	for _, h := range opts.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(h))
+	}
+	switch opts.Format {
+	case FormatJSONL:
+		return &jsonlEncoder{w: w, enc: json.NewEncoder(w), headers: headers}
+	case FormatGob:
+		return &gobEncoder{enc: vegeta.NewEncoder(w)}
+	default:
+		return &csvEncoder{w: w, version: opts.SchemaVersion, headers: headers}
+	}
+}
+
+// resultDecoder reads the results written by resultEncoder back, and gives back io.EOF at the end.
+type resultDecoder func() (id string, res Result, err error)
+
+// newResultDecoder gives back the decoder of the given format.
+// The runs in the index are needed only for the gob format, whose results don't hold the run id.
+func newResultDecoder(f Format, r io.Reader, runs []IndexEntry) (resultDecoder, error) {
+	switch f {
+	case FormatJSONL:
+		return newJSONLDecoder(r), nil
+	case FormatGob:
+		return newGobDecoder(r, runs), nil
+	default:
+		return newCSVDecoder(r)
+	}
+}
+
+type csvEncoder struct {
+	w       io.Writer
+	writer  *csv.Writer
+	version int
+	// headers are the canonical names of the response headers to be written, only in the schema 2.
+	headers []string
+}
+
+func (e *csvEncoder) header() []string {
+	if e.version < SchemaV2 {
+		return resultsHeader
+	}
+	header := append([]string{}, resultsHeaderV2...)
+	for _, h := range e.headers {
+		header = append(header, headerColumnPrefix+h)
+	}
+	return header
+}
+
+func (e *csvEncoder) begin(existing io.Reader) error {
+	e.writer = csv.NewWriter(e.w)
+	if existing == nil {
+		return e.writer.Write(e.header())
+	}
+	// The existing results are carried over as is, only if they have the same columns.
+	br := bufio.NewReader(existing)
+	line, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	header, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return fmt.Errorf("failed to read the existing header: %w", err)
+	}
+	if !equalStrings(header, e.header()) {
+		return fmt.Errorf("the existing header %q differs from %q", header, e.header())
+	}
+	if _, err := io.WriteString(e.w, line); err != nil {
+		return err
+	}
+	_, err = io.Copy(e.w, br)
+	return err
+}
+
+func (e *csvEncoder) encode(id string, res Result) error {
+	record := []string{
+		id,
+		res.Timestamp.Format(time.RFC3339Nano),
+		formatLatencyNS(res.LatencyNS),
+		res.URL,
+		res.Method,
+		strconv.FormatUint(uint64(res.StatusCode), 10),
+	}
+	if e.version >= SchemaV2 {
+		record = append(record,
+			strconv.FormatUint(res.Seq, 10),
+			res.Attack,
+			strconv.FormatUint(res.BytesIn, 10),
+			strconv.FormatUint(res.BytesOut, 10),
+			res.Error,
+		)
+		for _, h := range e.headers {
+			record = append(record, strings.Join(res.Headers.Values(h), ", "))
+		}
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func newCSVDecoder(r io.Reader) (resultDecoder, error) {
+	reader := csv.NewReader(r)
+	// The number of columns differs between the schemas.
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results header: %w", err)
+	}
+	headers, err := parseResultsHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	line := 1
+	return func() (string, Result, error) {
+		line++
+		record, err := reader.Read()
+		if err != nil {
+			return "", Result{}, err
+		}
+		if len(record) != len(header) {
+			return "", Result{}, fmt.Errorf("bad result at line %d: %d fields given, but %d expected", line, len(record), len(header))
+		}
+		res, err := parseResult(record, headers)
+		if err != nil {
+			return "", Result{}, fmt.Errorf("bad result at line %d: %w", line, err)
+		}
+		return record[0], res, nil
+	}, nil
+}
+
+// parseResultsHeader gives back the names of the response headers in the given header of the results file,
+// which is nil for the schema 1.
+func parseResultsHeader(header []string) ([]string, error) {
+	if equalStrings(header, resultsHeader) {
+		return nil, nil
+	}
+	if len(header) < len(resultsHeaderV2) || !equalStrings(header[:len(resultsHeaderV2)], resultsHeaderV2) {
+		return nil, fmt.Errorf("unknown header %q", header)
+	}
+	headers := []string{}
+	for _, column := range header[len(resultsHeaderV2):] {
+		name, ok := strings.CutPrefix(column, headerColumnPrefix)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		headers = append(headers, name)
+	}
+	return headers, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonlResult is a line of the JSON Lines results file.
+type jsonlResult struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// LatencyNS is null if the latency isn't a finite number.
+	LatencyNS  *int64 `json:"latency_ns"`
+	URL        string `json:"url"`
+	Method     string `json:"method"`
+	StatusCode uint16 `json:"status_code"`
+	Seq        uint64 `json:"seq"`
+	Attack     string `json:"attack"`
+	BytesIn    uint64 `json:"bytes_in"`
+	BytesOut   uint64 `json:"bytes_out"`
+	Error      string `json:"error"`
+	// Headers holds the selected response headers only.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type jsonlEncoder struct {
+	w       io.Writer
+	enc     *json.Encoder
+	headers []string
+}
+
+func (e *jsonlEncoder) begin(existing io.Reader) error {
+	if existing == nil {
+		return nil
+	}
+	_, err := io.Copy(e.w, existing)
+	return err
+}
+
+func (e *jsonlEncoder) encode(id string, res Result) error {
+	r := jsonlResult{
+		ID:         id,
+		Timestamp:  res.Timestamp,
+		URL:        res.URL,
+		Method:     res.Method,
+		StatusCode: res.StatusCode,
+		Seq:        res.Seq,
+		Attack:     res.Attack,
+		BytesIn:    res.BytesIn,
+		BytesOut:   res.BytesOut,
+		Error:      res.Error,
+	}
+	if len(e.headers) > 0 {
+		r.Headers = make(map[string]string, len(e.headers))
+		for _, h := range e.headers {
+			r.Headers[h] = strings.Join(res.Headers.Values(h), ", ")
+		}
+	}
+	if !math.IsNaN(res.LatencyNS) && !math.IsInf(res.LatencyNS, 0) {
+		latency := int64(res.LatencyNS)
+		r.LatencyNS = &latency
+	}
+	return e.enc.Encode(r)
+}
+
+func (e *jsonlEncoder) flush() error {
+	return nil
+}
+
+func newJSONLDecoder(r io.Reader) resultDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	line := 0
+	return func() (string, Result, error) {
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var r jsonlResult
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				return "", Result{}, fmt.Errorf("bad result at line %d: %w", line, err)
+			}
+			latency := math.NaN()
+			if r.LatencyNS != nil {
+				latency = float64(*r.LatencyNS)
+			}
+			var headers http.Header
+			if len(r.Headers) > 0 {
+				headers = make(http.Header, len(r.Headers))
+				for k, v := range r.Headers {
+					headers.Set(k, v)
+				}
+			}
+			return r.ID, Result{
+				Timestamp:  r.Timestamp,
+				LatencyNS:  latency,
+				URL:        r.URL,
+				Method:     r.Method,
+				StatusCode: r.StatusCode,
+				Seq:        r.Seq,
+				Attack:     r.Attack,
+				BytesIn:    r.BytesIn,
+				BytesOut:   r.BytesOut,
+				Error:      r.Error,
+				Headers:    headers,
+			}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return "", Result{}, err
+		}
+		return "", Result{}, io.EOF
+	}
+}
+
+// gobEncoder writes vegeta.Result with the sequence within the run, leaving the attack name as vegeta set it
+// so that the results can still be told apart by stage. The run id isn't written, so the results of each run
+// are found by the ResultCount of the runs in the index, in the order they were appended.
+type gobEncoder struct {
+	enc vegeta.Encoder
+	seq uint64
+}
+
+// begin re-encodes the existing results, since a gob stream can't be appended by another encoder.
+func (e *gobEncoder) begin(existing io.Reader) error {
+	if existing == nil {
+		return nil
+	}
+	dec := vegeta.NewDecoder(existing)
+	for {
+		var r vegeta.Result
+		if err := dec.Decode(&r); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode the existing results: %w", err)
+		}
+		if err := e.enc.Encode(&r); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *gobEncoder) encode(id string, res Result) error {
+	r := &vegeta.Result{
+		Attack:    res.Attack,
+		Seq:       e.seq,
+		Code:      res.StatusCode,
+		Timestamp: res.Timestamp,
+		BytesOut:  res.BytesOut,
+		BytesIn:   res.BytesIn,
+		Error:     res.Error,
+		Method:    res.Method,
+		URL:       res.URL,
+	}
+	if !math.IsNaN(res.LatencyNS) && !math.IsInf(res.LatencyNS, 0) {
+		r.Latency = time.Duration(res.LatencyNS)
+	}
+	e.seq++
+	return e.enc.Encode(r)
+}
+
+func (e *gobEncoder) flush() error {
+	return nil
+}
+
+// newGobDecoder gives back the decoder which takes the results of each of the given runs in order, as many as its ResultCount.
+func newGobDecoder(r io.Reader, runs []IndexEntry) resultDecoder {
+	dec := vegeta.NewDecoder(r)
+	var i int
+	var taken uint64
+	return func() (string, Result, error) {
+		var r vegeta.Result
+		if err := dec.Decode(&r); err != nil {
+			return "", Result{}, err
+		}
+		for i < len(runs) && taken == runs[i].ResultCount {
+			i++
+			taken = 0
+		}
+		if i == len(runs) {
+			return "", Result{}, errors.New("more results found than the runs in the index wrote")
+		}
+		taken++
+		return runs[i].ID, Result{
+			Timestamp:  r.Timestamp,
+			LatencyNS:  float64(r.Latency.Nanoseconds()),
+			URL:        r.URL,
+			Method:     r.Method,
+			StatusCode: r.Code,
+			Seq:        r.Seq,
+			Attack:     r.Attack,
+			BytesIn:    r.BytesIn,
+			BytesOut:   r.BytesOut,
+			Error:      r.Error,
+		}, nil
+	}
+}
@@ -0,0 +1,175 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		got, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		assert.Equal(t, f, got)
+	}
+	_, err := ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestFileExporter_JSONL(t *testing.T) {
+	dir := t.TempDir()
+	run, err := NewFileExporterWithOptions(dir, FileOptions{Format: FormatJSONL}).StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	require.NoError(t, run.WriteResult(Result{Timestamp: timestamp, LatencyNS: 1000, StatusCode: 200, BytesIn: 10, BytesOut: 2}))
+	require.NoError(t, run.WriteResult(Result{Timestamp: timestamp, LatencyNS: math.NaN(), StatusCode: 0, Error: "connection refused"}))
+	require.NoError(t, run.Close(Summary{}))
+
+	want := `{"id":"run-1","timestamp":"2021-03-13T06:20:43Z","latency_ns":1000,"url":"https://example.com/","method":"GET","status_code":200,"seq":0,"attack":"","bytes_in":10,"bytes_out":2,"error":""}
+{"id":"run-1","timestamp":"2021-03-13T06:20:43Z","latency_ns":null,"url":"https://example.com/","method":"GET","status_code":0,"seq":0,"attack":"","bytes_in":0,"bytes_out":0,"error":"connection refused"}
+`
+	assert.Equal(t, want, string(readFile(t, filepath.Join(dir, "results.jsonl"))))
+	_, err = os.Stat(filepath.Join(dir, summaryFilename("run-1")))
+	assert.NoError(t, err)
+}
+
+func TestFileExporter_Gob(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewFileExporterWithOptions(dir, FileOptions{Format: FormatGob})
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	// The attack names are the stages each result came from, and run-2 has no results.
+	attacks := map[string][]string{
+		"run-1": {"warm-up", "spike"},
+		"run-2": nil,
+		"run-3": {"main"},
+	}
+	for _, id := range []string{"run-1", "run-2", "run-3"} {
+		run, err := exporter.StartRun(Meta{ID: id, TargetURL: "https://example.com/", Method: "GET"})
+		require.NoError(t, err)
+		for _, attack := range attacks[id] {
+			require.NoError(t, run.WriteResult(Result{Timestamp: timestamp, LatencyNS: 1000, StatusCode: 500, Attack: attack, BytesIn: 10, Error: "500 Internal Server Error"}))
+		}
+		require.NoError(t, run.Close(Summary{}))
+	}
+
+	file, err := os.Open(filepath.Join(dir, "results.gob"))
+	require.NoError(t, err)
+	defer file.Close()
+	// It has to be readable by vegeta as a single stream, even after appended.
+	dec := vegeta.NewDecoder(file)
+	var got []vegeta.Result
+	for {
+		var r vegeta.Result
+		err := dec.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, r)
+	}
+	require.Len(t, got, 3)
+	assert.Equal(t, vegeta.Result{
+		Attack:    "spike",
+		Seq:       1,
+		Code:      500,
+		Timestamp: timestamp,
+		Latency:   time.Microsecond,
+		BytesIn:   10,
+		Error:     "500 Internal Server Error",
+		Method:    "GET",
+		URL:       "https://example.com/",
+	}, got[1])
+	assert.Equal(t, "warm-up", got[0].Attack)
+	assert.Equal(t, "main", got[2].Attack)
+
+	// The runs are told apart by the index.
+	runs, err := NewFileReader(dir).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, "run-1", runs[0].ID)
+	require.Len(t, runs[0].Results, 2)
+	assert.Equal(t, "spike", runs[0].Results[1].Attack)
+	assert.Equal(t, "run-2", runs[1].ID)
+	assert.Empty(t, runs[1].Results)
+	assert.Equal(t, "run-3", runs[2].ID)
+	require.Len(t, runs[2].Results, 1)
+	assert.Equal(t, "main", runs[2].Results[0].Attack)
+}
+
+func TestFileReader_Formats(t *testing.T) {
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	for _, f := range []Format{FormatJSONL, FormatGob} {
+		t.Run(string(f), func(t *testing.T) {
+			dir := t.TempDir()
+			run, err := NewFileExporterWithOptions(dir, FileOptions{Format: f}).StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+			require.NoError(t, err)
+			want := Result{Timestamp: timestamp, LatencyNS: 1000, URL: "https://example.com/", Method: "GET", StatusCode: 200, BytesIn: 10, BytesOut: 2, Error: "oops"}
+			require.NoError(t, run.WriteResult(want))
+			require.NoError(t, run.Close(Summary{Requests: RequestsSummary{Count: 1}}))
+
+			runs, err := NewFileReader(dir).Runs()
+			require.NoError(t, err)
+			require.Len(t, runs, 1)
+			assert.Equal(t, "run-1", runs[0].ID)
+			require.Len(t, runs[0].Results, 1)
+			assert.True(t, want.Timestamp.Equal(runs[0].Results[0].Timestamp))
+			runs[0].Results[0].Timestamp = want.Timestamp
+			assert.Equal(t, want, runs[0].Results[0])
+			require.NotNil(t, runs[0].Summary)
+		})
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "results.jsonl"), []byte("{\n"), 0o644))
+	_, err := NewFileReader(dir).Runs()
+	assert.Error(t, err, "bad JSON line")
+}
+
+func TestFileExporter_SchemaV2(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewFileExporterWithOptions(dir, FileOptions{SchemaVersion: SchemaV2, Headers: []string{"content-type", "X-Cache"}})
+	run, err := exporter.StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	want := Result{
+		Timestamp:  timestamp,
+		LatencyNS:  1000,
+		URL:        "https://example.com/",
+		Method:     "GET",
+		StatusCode: 500,
+		Seq:        3,
+		Attack:     "warmup",
+		BytesIn:    10,
+		BytesOut:   2,
+		Error:      "500 Internal Server Error",
+		Headers:    http.Header{"Content-Type": {"text/plain"}, "X-Cache": {"MISS"}, "Server": {"nginx"}},
+	}
+	require.NoError(t, run.WriteResult(want))
+	require.NoError(t, run.Close(Summary{}))
+
+	wantCSV := `id,timestamp,latency_ns,url,method,status_code,seq,attack,bytes_in,bytes_out,error,header:Content-Type,header:X-Cache
+run-1,2021-03-13T06:20:43Z,1000,https://example.com/,GET,500,3,warmup,10,2,500 Internal Server Error,text/plain,MISS
+`
+	assert.Equal(t, wantCSV, string(readFile(t, filepath.Join(dir, "results.csv"))))
+
+	runs, err := NewFileReader(dir).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Len(t, runs[0].Results, 1)
+	// Only the selected headers are exported.
+	want.Headers = http.Header{"Content-Type": {"text/plain"}, "X-Cache": {"MISS"}}
+	assert.Equal(t, want, runs[0].Results[0])
+
+	// Runs with another schema can't be appended to the same file.
+	_, err = NewFileExporter(dir).StartRun(Meta{ID: "run-2"})
+	assert.Error(t, err)
+}
@@ -3,12 +3,11 @@ package export
 import (
 	"bufio"
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"math"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,10 +16,18 @@ import (
 )
 
 const (
-	resultsFilename = "results.csv"
+	// SchemaV1 is the original CSV schema.
+	SchemaV1 = 1
+	// SchemaV2 is the CSV schema with the columns in resultsHeaderV2, followed by the selected response headers.
+	SchemaV2 = 2
+
+	headerColumnPrefix = "header:"
 )
 
-var resultsHeader = []string{"id", "timestamp", "latency_ns", "url", "method", "status_code"}
+var (
+	resultsHeader   = []string{"id", "timestamp", "latency_ns", "url", "method", "status_code"}
+	resultsHeaderV2 = append(resultsHeader[:len(resultsHeader):len(resultsHeader)], "seq", "attack", "bytes_in", "bytes_out", "error")
+)
 
 type Meta struct {
 	ID        string
@@ -28,6 +35,10 @@ type Meta struct {
 	Method    string
 	Rate      int
 	Duration  time.Duration
+	// StartedAt is when the run started, which is written to the index.
+	StartedAt time.Time
+	// Config is the attack configuration in JSON, written to "config-<id>.json" if given.
+	Config []byte
 }
 
 type Result struct {
@@ -36,6 +47,15 @@ type Result struct {
 	URL        string
 	Method     string
 	StatusCode uint16
+	// The rest aren't written in the CSV schema 1.
+	Seq uint64
+	// Attack is the name of the attack, like the stage name.
+	Attack   string
+	BytesIn  uint64
+	BytesOut uint64
+	Error    string
+	// Headers are the response headers, of which only the selected ones are written.
+	Headers http.Header
 }
 
 type Summary struct {
@@ -47,6 +67,47 @@ type Summary struct {
 	LatencyMS   LatencySummary     `json:"latency_ms"`
 	Bytes       BytesSummary       `json:"bytes"`
 	StatusCodes StatusCodesSummary `json:"status_codes"`
+	// Histogram holds the number of responses in each latency bucket, only when buckets were given.
+	Histogram []HistogramBucketSummary `json:"histogram,omitempty"`
+	// Targets holds the breakdown per target, only when there are multiple request shapes.
+	Targets []TargetBreakdownSummary `json:"targets,omitempty"`
+	// Stages holds the breakdown per stage, only when the run consisted of stages.
+	Stages []StageSummary `json:"stages,omitempty"`
+	// Checks holds the results of the assertions, only when any assertion was given.
+	Checks []CheckSummary `json:"checks,omitempty"`
+	// Aborted holds the reason, only when the run got aborted by an abort condition.
+	Aborted *AbortSummary `json:"aborted,omitempty"`
+	// Stopped is true only when the run got stopped by the user before it completed.
+	Stopped bool `json:"stopped,omitempty"`
+	// Errors is the unique errors returned by the targets.
+	Errors []string `json:"errors,omitempty"`
+	// Events holds what happened during the run in order, like rate changes.
+	Events []Event `json:"events,omitempty"`
+	// Comparison holds the differences from the baseline, only when a baseline was given.
+	Comparison *ComparisonSummary `json:"comparison,omitempty"`
+}
+
+const (
+	// EventTypeRateChange means the rate was changed during the run.
+	EventTypeRateChange = "rate_change"
+)
+
+// Event is what happened during the run.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	// ElapsedSeconds is the time elapsed since the run began.
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Type           string  `json:"type"`
+	// Rate is the new rate, only for "rate_change".
+	Rate int `json:"rate,omitempty"`
+}
+
+// HistogramBucketSummary is the number of responses whose latency is at least MinMS and less than MaxMS.
+type HistogramBucketSummary struct {
+	MinMS float64 `json:"min_ms"`
+	// MaxMS is omitted for the last bucket, which has no upper bound.
+	MaxMS float64 `json:"max_ms,omitempty"`
+	Count uint64  `json:"count"`
 }
 
 type TargetSummary struct {
@@ -54,9 +115,40 @@ type TargetSummary struct {
 	Method string `json:"method"`
 }
 
+type TargetBreakdownSummary struct {
+	URL         string             `json:"url"`
+	Method      string             `json:"method"`
+	Requests    RequestsSummary    `json:"requests"`
+	LatencyMS   LatencySummary     `json:"latency_ms"`
+	StatusCodes StatusCodesSummary `json:"status_codes"`
+}
+
+type StageSummary struct {
+	Name            string             `json:"name"`
+	Rate            int                `json:"rate"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Timing          TimingSummary      `json:"timing"`
+	Requests        RequestsSummary    `json:"requests"`
+	Throughput      float64            `json:"throughput"`
+	LatencyMS       LatencySummary     `json:"latency_ms"`
+	StatusCodes     StatusCodesSummary `json:"status_codes"`
+}
+
+type CheckSummary struct {
+	Assertion string `json:"assertion"`
+	Actual    string `json:"actual"`
+	Passed    bool   `json:"passed"`
+}
+
+type AbortSummary struct {
+	Condition string `json:"condition"`
+	Actual    string `json:"actual"`
+}
+
 type ParametersSummary struct {
 	Rate            int     `json:"rate"`
 	DurationSeconds float64 `json:"duration_seconds"`
+	LoadProfile     string  `json:"load_profile,omitempty"`
 }
 
 type TimingSummary struct {
@@ -118,11 +210,33 @@ func (s StatusCodesSummary) MarshalJSON() ([]byte, error) {
 }
 
 type FileExporter struct {
-	dir string
+	dir  string
+	opts FileOptions
+}
+
+// FileOptions are how FileExporter writes the results.
+type FileOptions struct {
+	// Format is FormatCSV if empty.
+	Format Format
+	// SchemaVersion is the version of the CSV schema, SchemaV1 if zero.
+	SchemaVersion int
+	// Headers are the names of the response headers to be written in the CSV schema 2 and JSON Lines.
+	Headers []string
 }
 
+// NewFileExporter gives back an exporter writing the results in the CSV schema 1.
 func NewFileExporter(dir string) *FileExporter {
-	return &FileExporter{dir: dir}
+	return NewFileExporterWithOptions(dir, FileOptions{})
+}
+
+func NewFileExporterWithOptions(dir string, opts FileOptions) *FileExporter {
+	if opts.Format == "" {
+		opts.Format = FormatCSV
+	}
+	if opts.SchemaVersion == 0 {
+		opts.SchemaVersion = SchemaV1
+	}
+	return &FileExporter{dir: dir, opts: opts}
 }
 
 type Run struct {
@@ -131,25 +245,37 @@ type Run struct {
 	resultsPath string
 	summaryPath string
 
-	resultsFile *os.File
-	resultsBuf  *bufio.Writer
-	resultsCSV  *csv.Writer
+	resultsFile    *os.File
+	resultsBuf     *bufio.Writer
+	resultsEncoder resultEncoder
 
 	tempResultsPath string
-	closed          bool
+	// count is the number of results written.
+	count  uint64
+	closed bool
 }
 
-func (e *FileExporter) StartRun(meta Meta) (*Run, error) {
+func (e *FileExporter) StartRun(meta Meta) (RunExporter, error) {
 	if meta.ID == "" {
 		return nil, errors.New("export run id is required")
 	}
 	if e.dir == "" {
 		return nil, errors.New("export directory is required")
 	}
-	resultsPath := filepath.Join(e.dir, resultsFilename)
+	format := e.opts.Format
+	resultsPath := filepath.Join(e.dir, format.resultsFilename())
+	// Results in different formats can't be read back as a whole.
+	for _, f := range Formats {
+		if f == format {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(e.dir, f.resultsFilename())); err == nil {
+			return nil, fmt.Errorf("%q already has results in the %s format", e.dir, f)
+		}
+	}
 	summaryPath := filepath.Join(e.dir, summaryFilename(meta.ID))
 
-	tmpFile, err := os.CreateTemp(e.dir, ".results.csv.")
+	tmpFile, err := os.CreateTemp(e.dir, "."+format.resultsFilename()+".")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp results file in %q: %w", e.dir, err)
 	}
@@ -160,7 +286,8 @@ func (e *FileExporter) StartRun(meta Meta) (*Run, error) {
 		return nil, fmt.Errorf("failed to chmod temp results file %q: %w", tempResultsPath, err)
 	}
 
-	var resultsExist bool
+	buf := bufio.NewWriter(tmpFile)
+	encoder := newResultEncoder(buf, e.opts)
 	info, err := os.Stat(resultsPath)
 	if err == nil {
 		if info.IsDir() {
@@ -168,14 +295,13 @@ func (e *FileExporter) StartRun(meta Meta) (*Run, error) {
 			_ = os.Remove(tempResultsPath)
 			return nil, fmt.Errorf("results path %q is a directory", resultsPath)
 		}
-		resultsExist = true
 		src, err := os.Open(resultsPath)
 		if err != nil {
 			_ = tmpFile.Close()
 			_ = os.Remove(tempResultsPath)
 			return nil, fmt.Errorf("failed to open results file %q: %w", resultsPath, err)
 		}
-		if _, err := io.Copy(tmpFile, src); err != nil {
+		if err := encoder.begin(src); err != nil {
 			_ = src.Close()
 			_ = tmpFile.Close()
 			_ = os.Remove(tempResultsPath)
@@ -190,16 +316,10 @@ func (e *FileExporter) StartRun(meta Meta) (*Run, error) {
 		_ = tmpFile.Close()
 		_ = os.Remove(tempResultsPath)
 		return nil, fmt.Errorf("failed to stat results file %q: %w", resultsPath, err)
-	}
-
-	buf := bufio.NewWriter(tmpFile)
-	writer := csv.NewWriter(buf)
-	if !resultsExist {
-		if err := writer.Write(resultsHeader); err != nil {
-			_ = tmpFile.Close()
-			_ = os.Remove(tempResultsPath)
-			return nil, fmt.Errorf("failed to write results header to %q: %w", resultsPath, err)
-		}
+	} else if err := encoder.begin(nil); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tempResultsPath)
+		return nil, fmt.Errorf("failed to write results header to %q: %w", resultsPath, err)
 	}
 
 	return &Run{
@@ -208,7 +328,7 @@ func (e *FileExporter) StartRun(meta Meta) (*Run, error) {
 		summaryPath:     summaryPath,
 		resultsFile:     tmpFile,
 		resultsBuf:      buf,
-		resultsCSV:      writer,
+		resultsEncoder:  encoder,
 		tempResultsPath: tempResultsPath,
 	}, nil
 }
@@ -225,18 +345,12 @@ func (r *Run) WriteResult(res Result) error {
 	if method == "" {
 		method = r.meta.Method
 	}
-	record := []string{
-		r.meta.ID,
-		res.Timestamp.Format(time.RFC3339Nano),
-		formatLatencyNS(res.LatencyNS),
-		url,
-		method,
-		strconv.FormatUint(uint64(res.StatusCode), 10),
-	}
-	if err := r.resultsCSV.Write(record); err != nil {
+	res.URL, res.Method = url, method
+	if err := r.resultsEncoder.encode(r.meta.ID, res); err != nil {
 		_ = r.Abort()
 		return fmt.Errorf("failed to write results to %q: %w", r.resultsPath, err)
 	}
+	r.count++
 	return nil
 }
 
@@ -244,8 +358,7 @@ func (r *Run) Close(summary Summary) error {
 	if r.closed {
 		return errors.New("export run already closed")
 	}
-	r.resultsCSV.Flush()
-	if err := r.resultsCSV.Error(); err != nil {
+	if err := r.resultsEncoder.flush(); err != nil {
 		_ = r.Abort()
 		return fmt.Errorf("failed to flush results to %q: %w", r.resultsPath, err)
 	}
@@ -268,6 +381,29 @@ func (r *Run) Close(summary Summary) error {
 	if err := writeSummary(r.summaryPath, summary); err != nil {
 		return err
 	}
+	var configName string
+	if len(r.meta.Config) > 0 {
+		configName = configFilename(r.meta.ID)
+		if err := writeJSON(filepath.Join(filepath.Dir(r.summaryPath), configName), "config", json.RawMessage(r.meta.Config)); err != nil {
+			return err
+		}
+	}
+	if err := appendIndex(filepath.Dir(r.resultsPath), IndexEntry{
+		ID:        r.meta.ID,
+		StartedAt: r.meta.StartedAt,
+		Target:    TargetSummary{URL: r.meta.TargetURL, Method: r.meta.Method},
+		Parameters: ParametersSummary{
+			Rate:            r.meta.Rate,
+			DurationSeconds: r.meta.Duration.Seconds(),
+			LoadProfile:     summary.Parameters.LoadProfile,
+		},
+		Summary:     filepath.Base(r.summaryPath),
+		Results:     filepath.Base(r.resultsPath),
+		ResultCount: r.count,
+		Config:      configName,
+	}); err != nil {
+		return err
+	}
 	r.closed = true
 	return nil
 }
@@ -285,37 +421,44 @@ func (r *Run) Abort() error {
 }
 
 func writeSummary(path string, summary Summary) error {
+	return writeJSON(path, "summary", summary)
+}
+
+// writeJSON atomically replaces the file at the given path with v encoded in JSON.
+// kind is what the file is, used in the temp file name and errors.
+func writeJSON(path, kind string, v any) error {
 	dir := filepath.Dir(path)
-	tmpFile, err := os.CreateTemp(dir, ".summary.")
+	tmpFile, err := os.CreateTemp(dir, "."+kind+".")
 	if err != nil {
-		return fmt.Errorf("failed to create temp summary file in %q: %w", dir, err)
+		return fmt.Errorf("failed to create temp %s file in %q: %w", kind, dir, err)
 	}
 	tmpPath := tmpFile.Name()
 	if err := tmpFile.Chmod(0o644); err != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to chmod temp summary file %q: %w", tmpPath, err)
+		return fmt.Errorf("failed to chmod temp %s file %q: %w", kind, tmpPath, err)
 	}
 
 	enc := json.NewEncoder(tmpFile)
 	enc.SetIndent("", "  ")
-	if err := enc.Encode(summary); err != nil {
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to encode summary to %q: %w", path, err)
+		return fmt.Errorf("failed to encode %s to %q: %w", kind, path, err)
 	}
 	if err := tmpFile.Sync(); err != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to sync summary file %q: %w", path, err)
+		return fmt.Errorf("failed to sync %s file %q: %w", kind, path, err)
 	}
 	if err := tmpFile.Close(); err != nil {
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to close summary file %q: %w", path, err)
+		return fmt.Errorf("failed to close %s file %q: %w", kind, path, err)
 	}
 	if err := os.Rename(tmpPath, path); err != nil {
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to replace summary file %q: %w", path, err)
+		return fmt.Errorf("failed to replace %s file %q: %w", kind, path, err)
 	}
 	return nil
 }
@@ -330,3 +473,7 @@ func formatLatencyNS(v float64) string {
 func summaryFilename(id string) string {
 	return fmt.Sprintf("summary-%s.json", id)
 }
+
+func configFilename(id string) string {
+	return fmt.Sprintf("config-%s.json", id)
+}
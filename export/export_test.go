@@ -93,7 +93,7 @@ func TestFileExporter_Basic(t *testing.T) {
 	require.NoError(t, run.Close(summary))
 
 	wantResults := readGolden(t, filepath.Join("..", "testdata", "export", "basic", "results.csv"))
-	gotResults := readFile(t, filepath.Join(dir, resultsFilename))
+	gotResults := readFile(t, filepath.Join(dir, FormatCSV.resultsFilename()))
 	require.Equal(t, string(wantResults), string(gotResults))
 
 	wantSummary := readGolden(t, filepath.Join("..", "testdata", "export", "basic", "summary-00000000-0000-0000-0000-000000000000.json"))
@@ -123,7 +123,7 @@ func TestFileExporter_Quotes(t *testing.T) {
 	require.NoError(t, run.Close(Summary{}))
 
 	wantResults := readGolden(t, filepath.Join("..", "testdata", "export", "quotes", "results.csv"))
-	gotResults := readFile(t, filepath.Join(dir, resultsFilename))
+	gotResults := readFile(t, filepath.Join(dir, FormatCSV.resultsFilename()))
 	require.Equal(t, string(wantResults), string(gotResults))
 }
 
@@ -142,7 +142,7 @@ func TestFileExporter_EmptyResults(t *testing.T) {
 	require.NoError(t, run.Close(Summary{}))
 
 	wantResults := readGolden(t, filepath.Join("..", "testdata", "export", "empty", "results.csv"))
-	gotResults := readFile(t, filepath.Join(dir, resultsFilename))
+	gotResults := readFile(t, filepath.Join(dir, FormatCSV.resultsFilename()))
 	require.Equal(t, string(wantResults), string(gotResults))
 }
 
@@ -168,7 +168,7 @@ func TestFileExporter_NaNInf(t *testing.T) {
 	require.NoError(t, run.Close(Summary{}))
 
 	wantResults := readGolden(t, filepath.Join("..", "testdata", "export", "naninf", "results.csv"))
-	gotResults := readFile(t, filepath.Join(dir, resultsFilename))
+	gotResults := readFile(t, filepath.Join(dir, FormatCSV.resultsFilename()))
 	require.Equal(t, string(wantResults), string(gotResults))
 }
 
@@ -207,13 +207,88 @@ func TestFileExporter_AppendsRuns(t *testing.T) {
 	}))
 	require.NoError(t, run2.Close(Summary{}))
 
-	records := readCSV(t, filepath.Join(dir, resultsFilename))
+	records := readCSV(t, filepath.Join(dir, FormatCSV.resultsFilename()))
 	require.Len(t, records, 3)
 	require.Equal(t, resultsHeader, records[0])
 	require.Equal(t, "aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa", records[1][0])
 	require.Equal(t, "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb", records[2][0])
 }
 
+func TestFileExporter_Index(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewFileExporter(dir)
+	started := time.Date(2021, 3, 13, 15, 20, 43, 0, time.UTC)
+
+	for i, id := range []string{"aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa", "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb"} {
+		run, err := exporter.StartRun(Meta{
+			ID:        id,
+			TargetURL: "https://example.com/",
+			Method:    "GET",
+			Rate:      50,
+			Duration:  2 * time.Second,
+			StartedAt: started.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		require.NoError(t, run.Close(Summary{Parameters: ParametersSummary{LoadProfile: "linear"}}))
+	}
+	// An aborted run isn't indexed.
+	run, err := exporter.StartRun(Meta{ID: "ccccccc3-cccc-cccc-cccc-cccccccccccc", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	require.NoError(t, run.Abort())
+
+	index, err := ReadIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, &Index{Runs: []IndexEntry{
+		{
+			ID:         "aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+			StartedAt:  started,
+			Target:     TargetSummary{URL: "https://example.com/", Method: "GET"},
+			Parameters: ParametersSummary{Rate: 50, DurationSeconds: 2, LoadProfile: "linear"},
+			Summary:    "summary-aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa.json",
+			Results:    "results.csv",
+		},
+		{
+			ID:         "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+			StartedAt:  started.Add(time.Minute),
+			Target:     TargetSummary{URL: "https://example.com/", Method: "GET"},
+			Parameters: ParametersSummary{Rate: 50, DurationSeconds: 2, LoadProfile: "linear"},
+			Summary:    "summary-bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb.json",
+			Results:    "results.csv",
+		},
+	}}, index)
+}
+
+func TestFileExporter_Config(t *testing.T) {
+	dir := t.TempDir()
+	id := "aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	run, err := NewFileExporter(dir).StartRun(Meta{ID: id, Config: []byte(`{"rate":50,"header":{"Authorization":["<redacted>"]}}`)})
+	require.NoError(t, err)
+	require.NoError(t, run.Close(Summary{}))
+
+	require.Equal(t, `{
+  "rate": 50,
+  "header": {
+    "Authorization": [
+      "<redacted>"
+    ]
+  }
+}
+`, string(readFile(t, filepath.Join(dir, configFilename(id)))))
+	index, err := ReadIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, configFilename(id), index.Runs[0].Config)
+}
+
+func TestFileExporter_MixedFormats(t *testing.T) {
+	dir := t.TempDir()
+	run, err := NewFileExporter(dir).StartRun(Meta{ID: "aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa"})
+	require.NoError(t, err)
+	require.NoError(t, run.Close(Summary{}))
+
+	_, err = NewFileExporterWithOptions(dir, FileOptions{Format: FormatJSONL}).StartRun(Meta{ID: "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb"})
+	require.Error(t, err)
+}
+
 func TestFileExporter_AtomicResultsWrite(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("chmod semantics are not reliable on windows")
@@ -221,7 +296,7 @@ func TestFileExporter_AtomicResultsWrite(t *testing.T) {
 
 	dir := t.TempDir()
 	original := []byte("id,timestamp,latency_ns,url,method,status_code\n")
-	resultsPath := filepath.Join(dir, resultsFilename)
+	resultsPath := filepath.Join(dir, FormatCSV.resultsFilename())
 	require.NoError(t, os.WriteFile(resultsPath, original, 0o644))
 
 	exporter := NewFileExporter(dir)
@@ -0,0 +1,64 @@
+package export
+
+import "errors"
+
+// Exporter starts exporting a run.
+type Exporter interface {
+	StartRun(meta Meta) (RunExporter, error)
+}
+
+// RunExporter exports the results of a run.
+type RunExporter interface {
+	WriteResult(res Result) error
+	// Close finishes the run with the given summary.
+	Close(summary Summary) error
+	// Abort discards the run if possible.
+	Abort() error
+}
+
+// MultiExporter exports runs to all the exporters.
+type MultiExporter []Exporter
+
+func (m MultiExporter) StartRun(meta Meta) (RunExporter, error) {
+	runs := make(multiRun, 0, len(m))
+	for _, e := range m {
+		run, err := e.StartRun(meta)
+		if err != nil {
+			_ = runs.Abort()
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+type multiRun []RunExporter
+
+func (m multiRun) WriteResult(res Result) error {
+	for _, run := range m {
+		if err := run.WriteResult(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiRun) Close(summary Summary) error {
+	var errs []error
+	for _, run := range m {
+		if err := run.Close(summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiRun) Abort() error {
+	var errs []error
+	for _, run := range m {
+		if err := run.Abort(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const indexFilename = "index.json"
+
+// Index is the history of the runs exported to a directory, in the order they finished.
+type Index struct {
+	Runs []IndexEntry `json:"runs"`
+}
+
+// IndexEntry is a run in the index.
+type IndexEntry struct {
+	ID         string            `json:"id"`
+	StartedAt  time.Time         `json:"started_at"`
+	Target     TargetSummary     `json:"target"`
+	Parameters ParametersSummary `json:"parameters"`
+	// Summary is the path to the summary file, relative to the directory.
+	Summary string `json:"summary"`
+	// Results is the path to the results file, relative to the directory.
+	Results string `json:"results"`
+	// ResultCount is the number of results the run wrote, which tells the runs in a gob results file apart.
+	ResultCount uint64 `json:"result_count"`
+	// Config is the path to the config file, relative to the directory. It's empty if no config was given.
+	Config string `json:"config,omitempty"`
+}
+
+// ReadIndex reads the index in the given export directory, which is empty if the index doesn't exist.
+func ReadIndex(dir string) (*Index, error) {
+	path := filepath.Join(dir, indexFilename)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index file %q: %w", path, err)
+	}
+	var index Index
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode index file %q: %w", path, err)
+	}
+	return &index, nil
+}
+
+// appendIndex adds the given entry to the index in the given directory, replacing the one with the same id.
+func appendIndex(dir string, entry IndexEntry) error {
+	index, err := ReadIndex(dir)
+	if err != nil {
+		return err
+	}
+	runs := index.Runs[:0]
+	for _, r := range index.Runs {
+		if r.ID != entry.ID {
+			runs = append(runs, r)
+		}
+	}
+	index.Runs = append(runs, entry)
+	return writeJSON(filepath.Join(dir, indexFilename), "index", index)
+}
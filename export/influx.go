@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// influxSink sends a point per request in the line protocol,
+// to the write endpoint of either InfluxDB 1.x (/write?db=...) or 2.x (/api/v2/write?org=...&bucket=...).
+type influxSink struct {
+	client *http.Client
+	url    string
+	// token is given as the password of the URL without the user name, which is sent as "Authorization: Token".
+	token string
+}
+
+func newInfluxSink(client *http.Client, rawURL string) (*influxSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	s := &influxSink{client: client}
+	if u.User != nil && u.User.Username() == "" {
+		s.token, _ = u.User.Password()
+		u.User = nil
+	}
+	q := u.Query()
+	if q.Get("precision") == "" {
+		q.Set("precision", "ns")
+		u.RawQuery = q.Encode()
+	}
+	s.url = u.String()
+	return s, nil
+}
+
+func (s *influxSink) send(ctx context.Context, meta Meta, results []Result) error {
+	var b bytes.Buffer
+	for _, r := range results {
+		writeInfluxLine(&b, meta.ID, r)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	if err := post(ctx, s.client, req); err != nil {
+		return fmt.Errorf("failed to write to %q: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
+
+func (s *influxSink) close() error {
+	return nil
+}
+
+// writeInfluxLine writes a line like:
+//
+//	ali_request,run_id=<id>,method=GET,url=<url>,status_code=200,attack=main latency_ns=1000i,bytes_in=10i,bytes_out=0i,error="" <timestamp>
+func writeInfluxLine(b *bytes.Buffer, id string, r Result) {
+	b.WriteString("ali_request")
+	for _, tag := range [][2]string{
+		{"run_id", id},
+		{"method", r.Method},
+		{"url", r.URL},
+		{"status_code", strconv.FormatUint(uint64(r.StatusCode), 10)},
+		{"attack", r.Attack},
+	} {
+		// Tags with empty values aren't allowed.
+		if tag[1] == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(tag[0])
+		b.WriteByte('=')
+		b.WriteString(influxTagEscaper.Replace(tag[1]))
+	}
+	b.WriteByte(' ')
+	if !math.IsNaN(r.LatencyNS) && !math.IsInf(r.LatencyNS, 0) {
+		fmt.Fprintf(b, "latency_ns=%di,", int64(r.LatencyNS))
+	}
+	fmt.Fprintf(b, "bytes_in=%di,bytes_out=%di,error=\"%s\" %d\n",
+		r.BytesIn, r.BytesOut, influxStringEscaper.Replace(r.Error), r.Timestamp.UnixNano())
+}
+
+var (
+	influxTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// prometheusSink sends the cumulative counters per target and status code,
+// along with the latency quantiles of each batch, with the remote-write protocol.
+type prometheusSink struct {
+	client *http.Client
+	url    string
+	now    func() time.Time
+	// counters are cumulative across the batches of the run, as Prometheus counters are expected to be.
+	counters map[promSeriesKey]*promCounters
+}
+
+type promSeriesKey struct {
+	url, method, code string
+}
+
+type promCounters struct {
+	requests        uint64
+	errors          uint64
+	durationSeconds float64
+	bytesIn         uint64
+	bytesOut        uint64
+}
+
+// promSample is a sample of a time series, whose labels are sorted by name.
+type promSample struct {
+	labels [][2]string
+	value  float64
+}
+
+func newPrometheusSink(client *http.Client, url string) *prometheusSink {
+	return &prometheusSink{
+		client:   client,
+		url:      url,
+		now:      time.Now,
+		counters: make(map[promSeriesKey]*promCounters),
+	}
+}
+
+func (s *prometheusSink) send(ctx context.Context, meta Meta, results []Result) error {
+	latencies := make([]float64, 0, len(results))
+	for _, r := range results {
+		key := promSeriesKey{url: r.URL, method: r.Method, code: strconv.FormatUint(uint64(r.StatusCode), 10)}
+		c, ok := s.counters[key]
+		if !ok {
+			c = &promCounters{}
+			s.counters[key] = c
+		}
+		c.requests++
+		if r.Error != "" {
+			c.errors++
+		}
+		c.bytesIn += r.BytesIn
+		c.bytesOut += r.BytesOut
+		if !math.IsNaN(r.LatencyNS) && !math.IsInf(r.LatencyNS, 0) {
+			c.durationSeconds += r.LatencyNS / float64(time.Second)
+			latencies = append(latencies, r.LatencyNS/float64(time.Second))
+		}
+	}
+
+	keys := make([]promSeriesKey, 0, len(s.counters))
+	for k := range s.counters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].url != keys[j].url {
+			return keys[i].url < keys[j].url
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	var samples []promSample
+	for _, k := range keys {
+		c := s.counters[k]
+		labels := func(name string) [][2]string {
+			return [][2]string{{"__name__", name}, {"code", k.code}, {"method", k.method}, {"run_id", meta.ID}, {"url", k.url}}
+		}
+		samples = append(samples,
+			promSample{labels: labels("ali_requests_total"), value: float64(c.requests)},
+			promSample{labels: labels("ali_errors_total"), value: float64(c.errors)},
+			promSample{labels: labels("ali_request_duration_seconds_sum"), value: c.durationSeconds},
+			promSample{labels: labels("ali_bytes_in_total"), value: float64(c.bytesIn)},
+			promSample{labels: labels("ali_bytes_out_total"), value: float64(c.bytesOut)},
+		)
+	}
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		for _, q := range []struct {
+			label string
+			q     float64
+		}{{"0.5", 0.50}, {"0.9", 0.90}, {"0.95", 0.95}, {"0.99", 0.99}} {
+			i := int(math.Ceil(q.q*float64(len(latencies)))) - 1
+			samples = append(samples, promSample{
+				labels: [][2]string{{"__name__", "ali_request_duration_seconds"}, {"quantile", q.label}, {"run_id", meta.ID}},
+				value:  latencies[max(i, 0)],
+			})
+		}
+	}
+
+	body := snappy.Encode(nil, encodeWriteRequest(samples, s.now().UnixMilli()))
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if err := post(ctx, s.client, req); err != nil {
+		return fmt.Errorf("failed to remote-write to %q: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
+
+func (s *prometheusSink) close() error {
+	return nil
+}
+
+// encodeWriteRequest encodes the samples into the WriteRequest protobuf message of the remote-write protocol:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(samples []promSample, timestampMS int64) []byte {
+	var req []byte
+	for _, s := range samples {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = appendProtoBytes(label, 1, []byte(l[0]))
+			label = appendProtoBytes(label, 2, []byte(l[1]))
+			ts = appendProtoBytes(ts, 1, label)
+		}
+		var sample []byte
+		sample = binary.AppendUvarint(sample, 1<<3|1) // fixed64
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(s.value))
+		sample = binary.AppendUvarint(sample, 2<<3|0) // varint
+		sample = binary.AppendUvarint(sample, uint64(timestampMS))
+		ts = appendProtoBytes(ts, 2, sample)
+		req = appendProtoBytes(req, 1, ts)
+	}
+	return req
+}
+
+// appendProtoBytes appends the length-delimited field.
+func appendProtoBytes(b []byte, field uint64, v []byte) []byte {
+	b = binary.AppendUvarint(b, field<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+var errNoResultsFile = errors.New("no results file found")
+
+// FileReader reads the runs written by FileExporter back from the export directory.
+type FileReader struct {
+	dir string
+}
+
+func NewFileReader(dir string) *FileReader {
+	return &FileReader{dir: dir}
+}
+
+// RunRecord is a run read back from the export directory.
+type RunRecord struct {
+	ID      string
+	Results []Result
+	// Summary is nil if the summary file of the run doesn't exist.
+	Summary *Summary
+}
+
+// Runs gives back all the runs in the index in the order they finished, followed by the ones
+// only found in the results file, such as the ones exported before the index or never finished.
+func (r *FileReader) Runs() ([]*RunRecord, error) {
+	index, err := ReadIndex(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*RunRecord
+	byID := make(map[string]*RunRecord)
+	for _, entry := range index.Runs {
+		run := &RunRecord{ID: entry.ID}
+		byID[entry.ID] = run
+		runs = append(runs, run)
+	}
+	if err := r.readResults(index.Runs, func(id string, res Result) {
+		run, ok := byID[id]
+		if !ok {
+			run = &RunRecord{ID: id}
+			byID[id] = run
+			runs = append(runs, run)
+		}
+		run.Results = append(run.Results, res)
+	}, len(runs) > 0); err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		summary, err := r.readSummary(run.ID)
+		if err != nil {
+			return nil, err
+		}
+		run.Summary = summary
+	}
+	return runs, nil
+}
+
+// readResults calls add for every result in the results file.
+// A missing results file is allowed only if optional is true, since the runs in the index may have no results.
+func (r *FileReader) readResults(runs []IndexEntry, add func(id string, res Result), optional bool) error {
+	resultsPath, format, err := r.resultsPath()
+	if errors.Is(err, errNoResultsFile) && optional {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(resultsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open results file %q: %w", resultsPath, err)
+	}
+	defer file.Close()
+
+	decode, err := newResultDecoder(format, file, runs)
+	if err != nil {
+		return fmt.Errorf("results file %q: %w", resultsPath, err)
+	}
+	for {
+		id, res, err := decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read results from %q: %w", resultsPath, err)
+		}
+		add(id, res)
+	}
+}
+
+// Run gives back the run with the given id.
+func (r *FileReader) Run(id string) (*RunRecord, error) {
+	runs, err := r.Runs()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("run %q not found in %q", id, r.dir)
+}
+
+// resultsPath gives back the path to the results file in whichever format is found first.
+func (r *FileReader) resultsPath() (string, Format, error) {
+	for _, f := range Formats {
+		path := filepath.Join(r.dir, f.resultsFilename())
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, f, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("failed to stat results file %q: %w", path, err)
+		}
+	}
+	return "", "", fmt.Errorf("%w in %q", errNoResultsFile, r.dir)
+}
+
+func (r *FileReader) readSummary(id string) (*Summary, error) {
+	path := filepath.Join(r.dir, summaryFilename(id))
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary file %q: %w", path, err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode summary file %q: %w", path, err)
+	}
+	return &summary, nil
+}
+
+// parseResult parses a record in the results file, which is the reverse of csvEncoder.encode.
+// headers are the names of the response headers in the record, which is nil for the schema 1.
+func parseResult(record []string, headers []string) (Result, error) {
+	want := len(resultsHeader)
+	if headers != nil {
+		want = len(resultsHeaderV2) + len(headers)
+	}
+	if len(record) != want {
+		return Result{}, fmt.Errorf("%d fields given, but %d expected", len(record), want)
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, record[1])
+	if err != nil {
+		return Result{}, fmt.Errorf("bad timestamp: %w", err)
+	}
+	// An empty latency means it wasn't a finite number.
+	latency := math.NaN()
+	if record[2] != "" {
+		latency, err = strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return Result{}, fmt.Errorf("bad latency: %w", err)
+		}
+	}
+	code, err := strconv.ParseUint(record[5], 10, 16)
+	if err != nil {
+		return Result{}, fmt.Errorf("bad status code: %w", err)
+	}
+	res := Result{
+		Timestamp:  timestamp,
+		LatencyNS:  latency,
+		URL:        record[3],
+		Method:     record[4],
+		StatusCode: uint16(code),
+	}
+	if headers == nil {
+		return res, nil
+	}
+	if res.Seq, err = strconv.ParseUint(record[6], 10, 64); err != nil {
+		return Result{}, fmt.Errorf("bad seq: %w", err)
+	}
+	res.Attack = record[7]
+	if res.BytesIn, err = strconv.ParseUint(record[8], 10, 64); err != nil {
+		return Result{}, fmt.Errorf("bad bytes_in: %w", err)
+	}
+	if res.BytesOut, err = strconv.ParseUint(record[9], 10, 64); err != nil {
+		return Result{}, fmt.Errorf("bad bytes_out: %w", err)
+	}
+	res.Error = record[10]
+	if len(headers) > 0 {
+		res.Headers = make(http.Header, len(headers))
+		for i, h := range headers {
+			res.Headers.Set(h, record[len(resultsHeaderV2)+i])
+		}
+	}
+	return res, nil
+}
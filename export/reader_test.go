@@ -0,0 +1,137 @@
+package export
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileReader_Golden(t *testing.T) {
+	runs, err := NewFileReader(filepath.Join("..", "testdata", "export", "basic")).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000000", runs[0].ID)
+	assert.Len(t, runs[0].Results, 3)
+	assert.Equal(t, Result{
+		Timestamp:  time.Date(2021, 3, 13, 15, 20, 43, 0, time.FixedZone("", 9*60*60)),
+		LatencyNS:  18234567,
+		URL:        "https://example.com/",
+		Method:     "GET",
+		StatusCode: 200,
+	}, runs[0].Results[0])
+	require.NotNil(t, runs[0].Summary)
+	assert.Equal(t, uint64(100), runs[0].Summary.Requests.Count)
+
+	runs, err = NewFileReader(filepath.Join("..", "testdata", "export", "quotes")).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, `https://example.com/hello, "world"`, runs[0].Results[0].URL)
+	assert.Nil(t, runs[0].Summary)
+
+	runs, err = NewFileReader(filepath.Join("..", "testdata", "export", "naninf")).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.True(t, math.IsNaN(runs[0].Results[0].LatencyNS))
+
+	runs, err = NewFileReader(filepath.Join("..", "testdata", "export", "empty")).Runs()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestFileReader_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewFileExporter(dir)
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	for i, id := range []string{"run-1", "run-2"} {
+		run, err := exporter.StartRun(Meta{ID: id, TargetURL: "https://example.com/", Method: "GET"})
+		require.NoError(t, err)
+		for j := 0; j <= i; j++ {
+			require.NoError(t, run.WriteResult(Result{Timestamp: timestamp, LatencyNS: 1000, StatusCode: 200}))
+		}
+		require.NoError(t, run.Close(Summary{Requests: RequestsSummary{Count: uint64(i + 1)}}))
+	}
+
+	reader := NewFileReader(dir)
+	runs, err := reader.Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run-1", runs[0].ID)
+	assert.Len(t, runs[0].Results, 1)
+	assert.Equal(t, "run-2", runs[1].ID)
+	assert.Len(t, runs[1].Results, 2)
+	assert.Equal(t, uint64(2), runs[1].Summary.Requests.Count)
+
+	run, err := reader.Run("run-2")
+	require.NoError(t, err)
+	assert.Equal(t, "run-2", run.ID)
+	assert.Equal(t, "https://example.com/", run.Results[0].URL)
+
+	_, err = reader.Run("run-3")
+	assert.Error(t, err)
+}
+
+func TestFileReader_RunWithoutResults(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewFileExporter(dir)
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	run, err := exporter.StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	require.NoError(t, run.WriteResult(Result{Timestamp: timestamp, LatencyNS: 1000, StatusCode: 200}))
+	require.NoError(t, run.Close(Summary{Requests: RequestsSummary{Count: 1}}))
+	run, err = exporter.StartRun(Meta{ID: "run-2", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	require.NoError(t, run.Close(Summary{}))
+
+	runs, err := NewFileReader(dir).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run-1", runs[0].ID)
+	assert.Len(t, runs[0].Results, 1)
+	assert.Equal(t, "run-2", runs[1].ID)
+	assert.Empty(t, runs[1].Results)
+	assert.NotNil(t, runs[1].Summary)
+
+	// The runs in the index are still listed without the results file.
+	require.NoError(t, os.Remove(filepath.Join(dir, FormatCSV.resultsFilename())))
+	runs, err = NewFileReader(dir).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Empty(t, runs[0].Results)
+	assert.NotNil(t, runs[0].Summary)
+}
+
+func TestFileReader_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unknown header",
+			content: "id,timestamp\n",
+		},
+		{
+			name:    "bad timestamp",
+			content: "id,timestamp,latency_ns,url,method,status_code\nid,yesterday,1,https://example.com/,GET,200\n",
+		},
+		{
+			name:    "bad status code",
+			content: "id,timestamp,latency_ns,url,method,status_code\nid,2021-03-13T15:20:43+09:00,1,https://example.com/,GET,ok\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, FormatCSV.resultsFilename()), []byte(tt.content), 0o644))
+			_, err := NewFileReader(dir).Runs()
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewFileReader(t.TempDir()).Runs()
+	assert.Error(t, err, "no results file")
+}
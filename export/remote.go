@@ -0,0 +1,245 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteKind is the kind of the remote service the results are streamed to.
+type RemoteKind string
+
+const (
+	// RemotePrometheus sends the counters and the latency quantiles with the Prometheus remote-write protocol.
+	RemotePrometheus RemoteKind = "prometheus"
+	// RemoteInflux sends a point per request in the InfluxDB line protocol over HTTP.
+	RemoteInflux RemoteKind = "influx"
+	// RemoteStatsD sends the counters and the timings to a StatsD server over UDP.
+	RemoteStatsD RemoteKind = "statsd"
+)
+
+const (
+	// DefaultRemoteFlushInterval is how often the results are sent by default.
+	DefaultRemoteFlushInterval = 5 * time.Second
+
+	// remoteBatchSize is the number of results which makes them sent before the next interval.
+	remoteBatchSize = 5000
+	// remoteMaxPending is the number of results kept while the remote service is slow, beyond which they get dropped.
+	remoteMaxPending = 100000
+	remoteTimeout    = 10 * time.Second
+)
+
+// RemoteConfig is where the results are streamed to.
+type RemoteConfig struct {
+	Kind RemoteKind
+	// Addr is the URL for RemotePrometheus and RemoteInflux, and host:port for RemoteStatsD.
+	Addr string
+}
+
+// ParseRemote parses the given string like "prometheus=http://localhost:9090/api/v1/write".
+func ParseRemote(s string) (RemoteConfig, error) {
+	kind, addr, ok := strings.Cut(s, "=")
+	if !ok || addr == "" {
+		return RemoteConfig{}, fmt.Errorf("remote %q must be in the form of <kind>=<address>", s)
+	}
+	cfg := RemoteConfig{Kind: RemoteKind(kind), Addr: addr}
+	switch cfg.Kind {
+	case RemotePrometheus, RemoteInflux:
+		u, err := url.Parse(addr)
+		if err != nil {
+			return RemoteConfig{}, fmt.Errorf("bad %s URL %q: %w", kind, addr, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return RemoteConfig{}, fmt.Errorf("bad %s URL %q: http or https URL expected", kind, addr)
+		}
+	case RemoteStatsD:
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return RemoteConfig{}, fmt.Errorf("bad statsd address %q: %w", addr, err)
+		}
+	default:
+		return RemoteConfig{}, fmt.Errorf("unknown remote %q; %q, %q or %q expected", kind, RemotePrometheus, RemoteInflux, RemoteStatsD)
+	}
+	return cfg, nil
+}
+
+// sink sends the results to a remote service. It's used by a single goroutine.
+type sink interface {
+	send(ctx context.Context, meta Meta, results []Result) error
+	close() error
+}
+
+// RemoteExporter streams the results to a remote service while attacking,
+// by sending them in batches periodically.
+type RemoteExporter struct {
+	cfg           RemoteConfig
+	flushInterval time.Duration
+	newSink       func() (sink, error)
+}
+
+func NewRemoteExporter(cfg RemoteConfig, flushInterval time.Duration) (*RemoteExporter, error) {
+	if flushInterval <= 0 {
+		flushInterval = DefaultRemoteFlushInterval
+	}
+	e := &RemoteExporter{cfg: cfg, flushInterval: flushInterval}
+	client := &http.Client{Timeout: remoteTimeout}
+	switch cfg.Kind {
+	case RemotePrometheus:
+		e.newSink = func() (sink, error) { return newPrometheusSink(client, cfg.Addr), nil }
+	case RemoteInflux:
+		e.newSink = func() (sink, error) { return newInfluxSink(client, cfg.Addr) }
+	case RemoteStatsD:
+		e.newSink = func() (sink, error) { return newStatsDSink(cfg.Addr) }
+	default:
+		return nil, fmt.Errorf("unknown remote %q", cfg.Kind)
+	}
+	return e, nil
+}
+
+func (e *RemoteExporter) StartRun(meta Meta) (RunExporter, error) {
+	s, err := e.newSink()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", e.cfg.Kind, err)
+	}
+	r := &remoteRun{
+		meta:     meta,
+		kind:     e.cfg.Kind,
+		sink:     s,
+		interval: e.flushInterval,
+		full:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		loopDone: make(chan struct{}),
+	}
+	go r.loop()
+	return r, nil
+}
+
+// remoteRun sends the results in the background, so that the attack isn't blocked by the remote service.
+// Sending is best-effort: failures are logged rather than failing the attack.
+type remoteRun struct {
+	meta     Meta
+	kind     RemoteKind
+	sink     sink
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []Result
+	dropped int
+	closed  bool
+
+	full     chan struct{}
+	stop     chan struct{}
+	loopDone chan struct{}
+}
+
+func (r *remoteRun) WriteResult(res Result) error {
+	if res.URL == "" {
+		res.URL = r.meta.TargetURL
+	}
+	if res.Method == "" {
+		res.Method = r.meta.Method
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("export run already closed")
+	}
+	if len(r.pending) >= remoteMaxPending {
+		r.dropped++
+		return nil
+	}
+	r.pending = append(r.pending, res)
+	if len(r.pending) >= remoteBatchSize {
+		select {
+		case r.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (r *remoteRun) loop() {
+	defer close(r.loopDone)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		case <-r.full:
+		}
+		r.flush()
+	}
+}
+
+func (r *remoteRun) flush() {
+	r.mu.Lock()
+	batch, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("dropped %d results since %s was too slow\n", dropped, r.kind)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	if err := r.sink.send(ctx, r.meta, batch); err != nil {
+		log.Printf("failed to send %d results to %s: %v\n", len(batch), r.kind, err)
+	}
+}
+
+// Close sends the rest of the results.
+func (r *remoteRun) Close(_ Summary) error {
+	if !r.finish() {
+		return errors.New("export run already closed")
+	}
+	r.flush()
+	return r.sink.close()
+}
+
+// Abort stops sending without the rest of the results, since the ones already sent can't be discarded.
+func (r *remoteRun) Abort() error {
+	if !r.finish() {
+		return nil
+	}
+	return r.sink.close()
+}
+
+// finish stops the background loop, and gives back false if already finished.
+func (r *remoteRun) finish() bool {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return false
+	}
+	r.closed = true
+	r.mu.Unlock()
+	close(r.stop)
+	<-r.loopDone
+	return true
+}
+
+// post sends the given request, and gives back an error unless the response is 2xx.
+func post(ctx context.Context, client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,379 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    RemoteConfig
+		wantErr bool
+	}{
+		{
+			name: "prometheus",
+			s:    "prometheus=http://localhost:9090/api/v1/write",
+			want: RemoteConfig{Kind: RemotePrometheus, Addr: "http://localhost:9090/api/v1/write"},
+		},
+		{
+			name: "influx with query",
+			s:    "influx=https://localhost:8086/api/v2/write?org=o&bucket=b",
+			want: RemoteConfig{Kind: RemoteInflux, Addr: "https://localhost:8086/api/v2/write?org=o&bucket=b"},
+		},
+		{
+			name: "statsd",
+			s:    "statsd=localhost:8125",
+			want: RemoteConfig{Kind: RemoteStatsD, Addr: "localhost:8125"},
+		},
+		{
+			name:    "no address",
+			s:       "statsd",
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			s:       "graphite=localhost:2003",
+			wantErr: true,
+		},
+		{
+			name:    "not a URL",
+			s:       "prometheus=localhost:9090",
+			wantErr: true,
+		},
+		{
+			name:    "no port",
+			s:       "statsd=localhost",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRemote(tt.s)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// recordingServer records the requests to it.
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newRecordingServer(t *testing.T) *recordingServer {
+	s := &recordingServer{received: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		s.received <- struct{}{}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testResults() []Result {
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	return []Result{
+		{Timestamp: timestamp, LatencyNS: float64(10 * time.Millisecond), StatusCode: 200, BytesIn: 10, Attack: "main"},
+		{Timestamp: timestamp.Add(time.Millisecond), LatencyNS: float64(30 * time.Millisecond), StatusCode: 500, BytesIn: 5, Error: `500 "Internal" Server Error`, Attack: "main"},
+	}
+}
+
+func TestRemoteExporter_Prometheus(t *testing.T) {
+	server := newRecordingServer(t)
+	exporter, err := NewRemoteExporter(RemoteConfig{Kind: RemotePrometheus, Addr: server.URL + "/api/v1/write"}, time.Hour)
+	require.NoError(t, err)
+	run, err := exporter.StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	for _, res := range testResults() {
+		require.NoError(t, run.WriteResult(res))
+	}
+	require.NoError(t, run.Close(Summary{}))
+
+	require.Len(t, server.requests, 1)
+	req := server.requests[0]
+	assert.Equal(t, "/api/v1/write", req.URL.Path)
+	assert.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", req.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+	decoded, err := snappy.Decode(nil, server.bodies[0])
+	require.NoError(t, err)
+	got := decodeWriteRequest(t, decoded)
+	assert.Equal(t, 1.0, got[`ali_requests_total{code="200",method="GET",run_id="run-1",url="https://example.com/"}`])
+	assert.Equal(t, 1.0, got[`ali_errors_total{code="500",method="GET",run_id="run-1",url="https://example.com/"}`])
+	assert.Equal(t, 0.03, got[`ali_request_duration_seconds_sum{code="500",method="GET",run_id="run-1",url="https://example.com/"}`])
+	assert.Equal(t, 10.0, got[`ali_bytes_in_total{code="200",method="GET",run_id="run-1",url="https://example.com/"}`])
+	assert.Equal(t, 0.01, got[`ali_request_duration_seconds{quantile="0.5",run_id="run-1"}`])
+	assert.Equal(t, 0.03, got[`ali_request_duration_seconds{quantile="0.99",run_id="run-1"}`])
+	assert.Len(t, got, 2*5+4)
+}
+
+func TestPrometheusSink_Cumulative(t *testing.T) {
+	server := newRecordingServer(t)
+	s := newPrometheusSink(http.DefaultClient, server.URL)
+	meta := Meta{ID: "run-1"}
+	results := []Result{{LatencyNS: 1, URL: "https://example.com/", Method: "GET", StatusCode: 200}}
+	require.NoError(t, s.send(t.Context(), meta, results))
+	require.NoError(t, s.send(t.Context(), meta, results))
+
+	decoded, err := snappy.Decode(nil, server.bodies[1])
+	require.NoError(t, err)
+	got := decodeWriteRequest(t, decoded)
+	assert.Equal(t, 2.0, got[`ali_requests_total{code="200",method="GET",run_id="run-1",url="https://example.com/"}`])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	err = newPrometheusSink(http.DefaultClient, failing.URL).send(t.Context(), meta, results)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+}
+
+func TestRemoteExporter_Influx(t *testing.T) {
+	server := newRecordingServer(t)
+	addr := strings.Replace(server.URL, "http://", "http://:secret@", 1) + "/api/v2/write?org=o&bucket=b"
+	// A short interval makes the results sent before closing.
+	exporter, err := NewRemoteExporter(RemoteConfig{Kind: RemoteInflux, Addr: addr}, 10*time.Millisecond)
+	require.NoError(t, err)
+	run, err := exporter.StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/a b", Method: "GET"})
+	require.NoError(t, err)
+	for _, res := range testResults() {
+		require.NoError(t, run.WriteResult(res))
+	}
+	select {
+	case <-server.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no results were sent periodically")
+	}
+	require.NoError(t, run.Close(Summary{}))
+
+	require.Len(t, server.requests, 1, "nothing is left to be sent on closing")
+	req := server.requests[0]
+	assert.Equal(t, "Token secret", req.Header.Get("Authorization"))
+	assert.Equal(t, "ns", req.URL.Query().Get("precision"))
+	assert.Equal(t, "b", req.URL.Query().Get("bucket"))
+	want := `ali_request,run_id=run-1,method=GET,url=https://example.com/a\ b,status_code=200,attack=main latency_ns=10000000i,bytes_in=10i,bytes_out=0i,error="" 1615616443000000000
+ali_request,run_id=run-1,method=GET,url=https://example.com/a\ b,status_code=500,attack=main latency_ns=30000000i,bytes_in=5i,bytes_out=0i,error="500 \"Internal\" Server Error" 1615616443001000000
+`
+	assert.Equal(t, want, string(server.bodies[0]))
+}
+
+func TestRemoteExporter_StatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	exporter, err := NewRemoteExporter(RemoteConfig{Kind: RemoteStatsD, Addr: conn.LocalAddr().String()}, time.Hour)
+	require.NoError(t, err)
+	run, err := exporter.StartRun(Meta{ID: "run-1", TargetURL: "https://example.com/", Method: "GET"})
+	require.NoError(t, err)
+	for _, res := range testResults() {
+		require.NoError(t, run.WriteResult(res))
+	}
+	require.NoError(t, run.Close(Summary{}))
+
+	buf := make([]byte, statsdMaxPacketSize)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	want := []string{
+		"ali.latency:10|ms",
+		"ali.latency:30|ms",
+		"ali.requests:2|c",
+		"ali.errors:1|c",
+		"ali.bytes_in:15|c",
+		"ali.bytes_out:0|c",
+		"ali.status.200:1|c",
+		"ali.status.500:1|c",
+	}
+	assert.Equal(t, want, strings.Split(string(buf[:n]), "\n"))
+}
+
+func TestStatsDSink_SplitsPackets(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	s, err := newStatsDSink(conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer s.close()
+
+	results := make([]Result, 200)
+	for i := range results {
+		results[i] = Result{LatencyNS: float64(time.Millisecond), StatusCode: 200}
+	}
+	require.NoError(t, s.send(t.Context(), Meta{}, results))
+
+	var lines int
+	buf := make([]byte, 64*1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for lines < len(results)+5 {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, n, statsdMaxPacketSize)
+		lines += len(strings.Split(string(buf[:n]), "\n"))
+	}
+	assert.Equal(t, len(results)+5, lines)
+}
+
+func TestRemoteExporter_Abort(t *testing.T) {
+	server := newRecordingServer(t)
+	exporter, err := NewRemoteExporter(RemoteConfig{Kind: RemoteInflux, Addr: server.URL}, time.Hour)
+	require.NoError(t, err)
+	run, err := exporter.StartRun(Meta{ID: "run-1"})
+	require.NoError(t, err)
+	require.NoError(t, run.WriteResult(testResults()[0]))
+	require.NoError(t, run.Abort())
+	require.NoError(t, run.Abort())
+	assert.Error(t, run.WriteResult(testResults()[0]))
+	assert.Error(t, run.Close(Summary{}))
+	assert.Empty(t, server.requests)
+}
+
+type fakeExporter struct {
+	err  error
+	runs []*fakeRunExporter
+}
+
+func (e *fakeExporter) StartRun(Meta) (RunExporter, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	run := &fakeRunExporter{}
+	e.runs = append(e.runs, run)
+	return run, nil
+}
+
+type fakeRunExporter struct {
+	results []Result
+	closed  bool
+	aborted bool
+}
+
+func (r *fakeRunExporter) WriteResult(res Result) error {
+	r.results = append(r.results, res)
+	return nil
+}
+
+func (r *fakeRunExporter) Close(Summary) error {
+	r.closed = true
+	return nil
+}
+
+func (r *fakeRunExporter) Abort() error {
+	r.aborted = true
+	return nil
+}
+
+func TestMultiExporter(t *testing.T) {
+	a, b := &fakeExporter{}, &fakeExporter{}
+	run, err := MultiExporter{a, b}.StartRun(Meta{})
+	require.NoError(t, err)
+	require.NoError(t, run.WriteResult(Result{StatusCode: 200}))
+	require.NoError(t, run.Close(Summary{}))
+	for _, e := range []*fakeExporter{a, b} {
+		require.Len(t, e.runs, 1)
+		assert.Len(t, e.runs[0].results, 1)
+		assert.True(t, e.runs[0].closed)
+	}
+
+	c := &fakeExporter{}
+	_, err = MultiExporter{c, &fakeExporter{err: errors.New("unreachable")}}.StartRun(Meta{})
+	require.Error(t, err)
+	require.Len(t, c.runs, 1)
+	assert.True(t, c.runs[0].aborted, "the started ones should be aborted")
+}
+
+// decodeWriteRequest decodes the WriteRequest into the values keyed by the series like `name{label="value"}`.
+func decodeWriteRequest(t *testing.T, b []byte) map[string]float64 {
+	t.Helper()
+	got := make(map[string]float64)
+	for _, ts := range protoFields(t, b) {
+		require.Equal(t, uint64(1), ts.num)
+		var name string
+		var labels []string
+		var value float64
+		for _, f := range protoFields(t, ts.bytes) {
+			switch f.num {
+			case 1:
+				label := protoFields(t, f.bytes)
+				require.Len(t, label, 2)
+				if string(label[0].bytes) == "__name__" {
+					name = string(label[1].bytes)
+					continue
+				}
+				labels = append(labels, string(label[0].bytes)+`="`+string(label[1].bytes)+`"`)
+			case 2:
+				sample := protoFields(t, f.bytes)
+				require.Len(t, sample, 2)
+				value = math.Float64frombits(sample[0].fixed64)
+				assert.Positive(t, sample[1].varint, "timestamp")
+			}
+		}
+		require.True(t, sort.StringsAreSorted(labels), "labels must be sorted")
+		got[name+"{"+strings.Join(labels, ",")+"}"] = value
+	}
+	return got
+}
+
+type protoField struct {
+	num     uint64
+	bytes   []byte
+	varint  uint64
+	fixed64 uint64
+}
+
+func protoFields(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		require.Positive(t, n)
+		b = b[n:]
+		f := protoField{num: key >> 3}
+		switch key & 7 {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			require.Positive(t, n)
+			b = b[n:]
+		case 1:
+			f.fixed64 = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			require.Positive(t, n)
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
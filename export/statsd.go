@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	statsdPrefix = "ali."
+	// statsdMaxPacketSize keeps a packet within the common MTU, so that it isn't fragmented.
+	statsdMaxPacketSize = 1432
+)
+
+// statsdSink sends the counters of each batch and the timing of each request, packing multiple metrics per packet.
+type statsdSink struct {
+	conn net.Conn
+}
+
+func newStatsDSink(addr string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn}, nil
+}
+
+func (s *statsdSink) send(ctx context.Context, _ Meta, results []Result) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+	var errors, bytesIn, bytesOut uint64
+	codes := make(map[uint16]uint64)
+	var metrics []string
+	for _, r := range results {
+		codes[r.StatusCode]++
+		if r.Error != "" {
+			errors++
+		}
+		bytesIn += r.BytesIn
+		bytesOut += r.BytesOut
+		if !math.IsNaN(r.LatencyNS) && !math.IsInf(r.LatencyNS, 0) {
+			ms := strconv.FormatFloat(r.LatencyNS/float64(time.Millisecond), 'f', -1, 64)
+			metrics = append(metrics, statsdPrefix+"latency:"+ms+"|ms")
+		}
+	}
+	metrics = append(metrics,
+		fmt.Sprintf("%srequests:%d|c", statsdPrefix, len(results)),
+		fmt.Sprintf("%serrors:%d|c", statsdPrefix, errors),
+		fmt.Sprintf("%sbytes_in:%d|c", statsdPrefix, bytesIn),
+		fmt.Sprintf("%sbytes_out:%d|c", statsdPrefix, bytesOut),
+	)
+	sortedCodes := make([]int, 0, len(codes))
+	for code := range codes {
+		sortedCodes = append(sortedCodes, int(code))
+	}
+	sort.Ints(sortedCodes)
+	for _, code := range sortedCodes {
+		metrics = append(metrics, fmt.Sprintf("%sstatus.%d:%d|c", statsdPrefix, code, codes[uint16(code)]))
+	}
+
+	var packet bytes.Buffer
+	for _, m := range metrics {
+		if packet.Len() > 0 && packet.Len()+1+len(m) > statsdMaxPacketSize {
+			if _, err := s.conn.Write(packet.Bytes()); err != nil {
+				return fmt.Errorf("failed to send to statsd: %w", err)
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(m)
+	}
+	if _, err := s.conn.Write(packet.Bytes()); err != nil {
+		return fmt.Errorf("failed to send to statsd: %w", err)
+	}
+	return nil
+}
+
+func (s *statsdSink) close() error {
+	return s.conn.Close()
+}
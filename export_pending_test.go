@@ -28,7 +28,7 @@ func TestExportCLI_NoExportTo_NoFilesCreated(t *testing.T) {
 	runGUI = func(string, storage.Reader, attacker.Attacker, gui.Options) error {
 		return nil
 	}
-	var gotExporter *export.FileExporter
+	var gotExporter export.Exporter
 	newAttacker = func(_ storage.Writer, _ string, opts *attacker.Options) (attacker.Attacker, error) {
 		gotExporter = opts.Exporter
 		if gotExporter != nil {
@@ -131,8 +131,53 @@ func TestExportCLI_ExistingDirFails(t *testing.T) {
 	require.Equal(t, "keep", string(content))
 }
 
+func TestExportCLI_AppendToExistingDir(t *testing.T) {
+	origRunGUI := runGUI
+	origNewAttacker := newAttacker
+	defer func() {
+		runGUI = origRunGUI
+		newAttacker = origNewAttacker
+	}()
+
+	runGUI = func(_ string, _ storage.Reader, a attacker.Attacker, _ gui.Options) error {
+		return a.Attack(context.Background(), make(chan *attacker.Metrics, 10))
+	}
+	ids := []string{"aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa", "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}
+	newAttacker = func(_ storage.Writer, target string, opts *attacker.Options) (attacker.Attacker, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return &exportingAttacker{
+			exporter: opts.Exporter,
+			meta:     export.Meta{ID: id, TargetURL: target, Method: opts.Method, Rate: opts.Rate, Duration: opts.Duration},
+			results:  []export.Result{{Timestamp: time.Date(2021, 3, 13, 15, 20, 43, 0, time.UTC), LatencyNS: 123, StatusCode: 200}},
+		}, nil
+	}
+
+	resultsDir := filepath.Join(t.TempDir(), "results")
+	for i := 0; i < 2; i++ {
+		buf := &bytes.Buffer{}
+		c := defaultCLI(buf)
+		c.exportTo = resultsDir
+		c.exportAppend = true
+		c.rate = 10
+		require.Equal(t, 0, c.run([]string{"https://example.com/"}), buf.String())
+	}
+
+	runs, err := export.NewFileReader(resultsDir).Runs()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	index, err := export.ReadIndex(resultsDir)
+	require.NoError(t, err)
+	require.Len(t, index.Runs, 2)
+	require.Equal(t, "aaaaaaa1-aaaa-aaaa-aaaa-aaaaaaaaaaaa", index.Runs[0].ID)
+	require.Equal(t, "bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb", index.Runs[1].ID)
+	require.Equal(t, "summary-bbbbbbb2-bbbb-bbbb-bbbb-bbbbbbbbbbbb.json", index.Runs[1].Summary)
+	require.Equal(t, "https://example.com/", index.Runs[1].Target.URL)
+	require.Equal(t, 10, index.Runs[1].Parameters.Rate)
+}
+
 type exportingAttacker struct {
-	exporter *export.FileExporter
+	exporter export.Exporter
 	meta     export.Meta
 	results  []export.Result
 	summary  export.Summary
@@ -163,13 +208,82 @@ func (e *exportingAttacker) Method() string {
 	return e.meta.Method
 }
 
+func (e *exportingAttacker) LoadProfile() attacker.LoadProfile {
+	return attacker.LoadProfile{}
+}
+
+func (e *exportingAttacker) TargetRate() float64 {
+	return float64(e.meta.Rate)
+}
+
+func (e *exportingAttacker) Stages() []attacker.Stage {
+	return nil
+}
+
+func (e *exportingAttacker) Buckets() []time.Duration {
+	return nil
+}
+
+func (e *exportingAttacker) Checks() []attacker.CheckResult {
+	return nil
+}
+
+func (e *exportingAttacker) Pause() {
+}
+
+func (e *exportingAttacker) Resume() {
+}
+
+func (e *exportingAttacker) Paused() bool {
+	return false
+}
+
+func (e *exportingAttacker) InFlight() uint64 {
+	return 0
+}
+
+func (e *exportingAttacker) SetRate(int) error {
+	return nil
+}
+
 func defaultCLI(buf *bytes.Buffer) *cli {
 	return &cli{
 		method:         "GET",
 		localAddress:   "0.0.0.0",
 		queryRange:     gui.DefaultQueryRange,
 		redrawInterval: gui.DefaultRedrawInterval,
+		exportFormat:   string(export.FormatCSV),
+		exportSchema:   export.SchemaV1,
 		stdout:         buf,
 		stderr:         buf,
 	}
 }
+
+func TestExportCLI_RemoteAlongWithFiles(t *testing.T) {
+	origRunGUI := runGUI
+	origNewAttacker := newAttacker
+	defer func() {
+		runGUI = origRunGUI
+		newAttacker = origNewAttacker
+	}()
+
+	runGUI = func(string, storage.Reader, attacker.Attacker, gui.Options) error {
+		return nil
+	}
+	var gotExporter export.Exporter
+	newAttacker = func(_ storage.Writer, _ string, opts *attacker.Options) (attacker.Attacker, error) {
+		gotExporter = opts.Exporter
+		return &attacker.FakeAttacker{}, nil
+	}
+
+	buf := &bytes.Buffer{}
+	c := defaultCLI(buf)
+	c.exportTo = filepath.Join(t.TempDir(), "results")
+	c.exportRemotes = []string{"statsd=127.0.0.1:8125", "influx=http://127.0.0.1:8086/write?db=ali"}
+	exitCode := c.run([]string{"https://example.com/"})
+	require.Equal(t, 0, exitCode)
+	exporters, ok := gotExporter.(export.MultiExporter)
+	require.True(t, ok)
+	require.Len(t, exporters, 3)
+	require.IsType(t, &export.FileExporter{}, exporters[2])
+}
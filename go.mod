@@ -6,6 +6,7 @@ toolchain go1.24.5
 
 require (
 	github.com/golang/mock v1.6.0
+	github.com/golang/snappy v1.0.0
 	github.com/miekg/dns v1.1.43
 	github.com/mum4k/termdash v0.16.0
 	github.com/nakabonne/tstorage v0.3.5
@@ -14,6 +15,7 @@ require (
 	github.com/tsenart/vegeta/v12 v12.8.4
 	go.uber.org/atomic v1.9.0
 	go.uber.org/goleak v1.1.12
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -31,5 +33,4 @@ require (
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/term v0.39.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
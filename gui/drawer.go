@@ -2,23 +2,31 @@ package gui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/barchart"
+	"github.com/mum4k/termdash/widgets/gauge"
 	"github.com/mum4k/termdash/widgets/linechart"
 	"github.com/mum4k/termdash/widgets/text"
 	"go.uber.org/atomic"
 
 	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
 	"github.com/nakabonne/ali/storage"
 )
 
 // drawer periodically queries data points from the storage and passes them to the termdash API.
 type drawer struct {
+	targetURL string
+	// stageNames are the names of stages in order, used to mark the stage boundaries.
+	stageNames []string
 	// specify the data points range to show on the UI
 	queryRange     time.Duration
 	redrawInterval time.Duration
@@ -29,6 +37,11 @@ type drawer struct {
 
 	// aims to avoid to perform multiple `appendChartValues`.
 	chartDrawing *atomic.Bool
+	// paused is true while the ongoing attack is paused.
+	paused atomic.Bool
+	// cancelAttack cancels the latest attack; calling it after the attack is over does nothing.
+	cancelMu     sync.Mutex
+	cancelAttack context.CancelCauseFunc
 
 	mu      sync.RWMutex
 	metrics *attacker.Metrics
@@ -36,6 +49,12 @@ type drawer struct {
 
 	errMu     sync.Mutex
 	exportErr error
+
+	abortConditions []attacker.AbortCondition
+	// baseline is shown along with the metrics if given.
+	baseline *export.Baseline
+	// clock gives back the time the charts end at, which is the current time if nil.
+	clock func() time.Time
 }
 
 // redrawCharts sets the values held by itself as chart values, at the specified interval as redrawInterval.
@@ -50,18 +69,28 @@ L:
 		case <-ctx.Done():
 			break L
 		case <-ticker.C:
-			end := time.Now()
+			end := d.now()
 			start := end.Add(-d.queryRange)
 
 			latencies, err := d.storage.Select(storage.LatencyMetricName, start, end)
 			if err != nil {
 				log.Printf("failed to select latency data points: %v\n", err)
 			}
+			xLabels := map[int]string{
+				0: "req",
+			}
+			if len(d.stageNames) > 0 {
+				stages, err := d.storage.Select(storage.StageMetricName, start, end)
+				if err != nil {
+					log.Printf("failed to select stage data points: %v\n", err)
+				}
+				for i, label := range d.stageBoundaries(stages) {
+					xLabels[i] = label
+				}
+			}
 			d.widgets.latencyChart.Series("latency", latencies,
 				linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(87))),
-				linechart.SeriesXLabels(map[int]string{
-					0: "req",
-				}),
+				linechart.SeriesXLabels(xLabels),
 			)
 
 			p50, err := d.storage.Select(storage.P50MetricName, start, end)
@@ -71,6 +100,11 @@ L:
 			d.widgets.percentilesChart.Series("p50", p50,
 				linechart.SeriesCellOpts(d.widgets.p50Legend.cellOpts...),
 			)
+			if d.baseline != nil {
+				d.widgets.percentilesChart.Series("baseline p50", baselineSeries(d.baseline.Summary.LatencyMS.P50, len(p50)),
+					linechart.SeriesCellOpts(d.widgets.p50Legend.baselineCellOpts...),
+				)
+			}
 
 			p90, err := d.storage.Select(storage.P90MetricName, start, end)
 			if err != nil {
@@ -79,6 +113,11 @@ L:
 			d.widgets.percentilesChart.Series("p90", p90,
 				linechart.SeriesCellOpts(d.widgets.p90Legend.cellOpts...),
 			)
+			if d.baseline != nil {
+				d.widgets.percentilesChart.Series("baseline p90", baselineSeries(d.baseline.Summary.LatencyMS.P90, len(p90)),
+					linechart.SeriesCellOpts(d.widgets.p90Legend.baselineCellOpts...),
+				)
+			}
 
 			p95, err := d.storage.Select(storage.P95MetricName, start, end)
 			if err != nil {
@@ -87,6 +126,11 @@ L:
 			d.widgets.percentilesChart.Series("p95", p95,
 				linechart.SeriesCellOpts(d.widgets.p95Legend.cellOpts...),
 			)
+			if d.baseline != nil {
+				d.widgets.percentilesChart.Series("baseline p95", baselineSeries(d.baseline.Summary.LatencyMS.P95, len(p95)),
+					linechart.SeriesCellOpts(d.widgets.p95Legend.baselineCellOpts...),
+				)
+			}
 
 			p99, err := d.storage.Select(storage.P99MetricName, start, end)
 			if err != nil {
@@ -95,44 +139,354 @@ L:
 			d.widgets.percentilesChart.Series("p99", p99,
 				linechart.SeriesCellOpts(d.widgets.p99Legend.cellOpts...),
 			)
+			if d.baseline != nil {
+				d.widgets.percentilesChart.Series("baseline p99", baselineSeries(d.baseline.Summary.LatencyMS.P99, len(p99)),
+					linechart.SeriesCellOpts(d.widgets.p99Legend.baselineCellOpts...),
+				)
+			}
+
+			d.redrawStatusChart(start, end)
+			d.redrawErrorCharts(start, end)
+			d.redrawRateChart(start, end)
+			d.redrawHeatMap(start, end)
 		}
 	}
 	d.chartDrawing.Store(false)
 }
 
+// redrawStatusChart draws the responses per second stacked by the status class,
+// where the line of each class is the sum of the counts of the classes up to it.
+func (d *drawer) redrawStatusChart(start, end time.Time) {
+	counts := make([][]float64, len(storage.StatusClasses))
+	for i, class := range storage.StatusClasses {
+		points, err := d.storage.SelectPoints(storage.LatencyMetricName, start, end, storage.Label{Name: storage.ClassLabelName, Value: class})
+		if err != nil {
+			log.Printf("failed to select %s data points: %v\n", class, err)
+		}
+		counts[i] = countPerSecond(points, start, end)
+	}
+	// The lines are drawn from the first bucket with any response.
+	first := firstNonZero(counts...)
+	stacked := make([]float64, len(counts[0])-first)
+	for i, class := range storage.StatusClasses {
+		for j := range stacked {
+			stacked[j] += counts[i][first+j]
+		}
+		values := make([]float64, len(stacked))
+		copy(values, stacked)
+		d.widgets.statusChart.Series(class, values,
+			linechart.SeriesCellOpts(d.widgets.statusLegends[i].cellOpts...),
+			linechart.SeriesXLabels(map[int]string{0: "sec"}),
+		)
+	}
+}
+
+// redrawErrorCharts draws the failed requests per second and their percentage of all requests in each second.
+// The ratio isn't drawn for the seconds without any request.
+func (d *drawer) redrawErrorCharts(start, end time.Time) {
+	points, err := d.storage.SelectPoints(storage.FailedMetricName, start, end)
+	if err != nil {
+		log.Printf("failed to select failed data points: %v\n", err)
+	}
+	totals := countPerSecond(points, start, end)
+	failures := sumPerSecond(points, start, end)
+	first := firstNonZero(totals)
+	totals, failures = totals[first:], failures[first:]
+
+	ratios := make([]float64, len(totals))
+	for i := range ratios {
+		if totals[i] == 0 {
+			ratios[i] = math.NaN()
+			continue
+		}
+		ratios[i] = failures[i] / totals[i] * 100
+	}
+	d.widgets.errorCountChart.Series("errors", failures,
+		linechart.SeriesCellOpts(cell.FgColor(cell.ColorRed)),
+		linechart.SeriesXLabels(map[int]string{0: "sec"}),
+	)
+	d.widgets.errorRatioChart.Series("error ratio", ratios,
+		linechart.SeriesCellOpts(cell.FgColor(cell.ColorYellow)),
+		linechart.SeriesXLabels(map[int]string{0: "sec"}),
+	)
+}
+
+// redrawRateChart draws the rate per second the attack was aiming at, the requests issued per second
+// and the successful responses per second, so that it's seen if the client or the target can't keep up.
+// The issued and successful ones can be counted a second late at the edges, as the storage keeps them in order.
+func (d *drawer) redrawRateChart(start, end time.Time) {
+	issuedPoints, err := d.storage.SelectPoints(storage.IssuedMetricName, start, end)
+	if err != nil {
+		log.Printf("failed to select issued data points: %v\n", err)
+	}
+	successPoints, err := d.storage.SelectPoints(storage.SuccessMetricName, start, end)
+	if err != nil {
+		log.Printf("failed to select success data points: %v\n", err)
+	}
+	targetPoints, err := d.storage.SelectPoints(storage.TargetRateMetricName, start, end)
+	if err != nil {
+		log.Printf("failed to select target rate data points: %v\n", err)
+	}
+	issued := countPerSecond(issuedPoints, start, end)
+	throughput := sumPerSecond(successPoints, start, end)
+	first := firstNonZero(issued, throughput)
+
+	// The target rate isn't known while replaying.
+	if len(targetPoints) > 0 {
+		counts := countPerSecond(targetPoints, start, end)
+		targets := sumPerSecond(targetPoints, start, end)
+		// The seconds without any request are filled with the last known target.
+		last := math.NaN()
+		for i := range targets {
+			if counts[i] > 0 {
+				last = targets[i] / counts[i]
+			}
+			targets[i] = last
+		}
+		d.widgets.rateChart.Series("target", targets[first:],
+			linechart.SeriesCellOpts(d.widgets.targetRateLegend.cellOpts...),
+			linechart.SeriesXLabels(map[int]string{0: "sec"}),
+		)
+	}
+	d.widgets.rateChart.Series("rate", issued[first:],
+		linechart.SeriesCellOpts(d.widgets.rateLegend.cellOpts...),
+		linechart.SeriesXLabels(map[int]string{0: "sec"}),
+	)
+	d.widgets.rateChart.Series("throughput", throughput[first:],
+		linechart.SeriesCellOpts(d.widgets.throughputLegend.cellOpts...),
+		linechart.SeriesXLabels(map[int]string{0: "sec"}),
+	)
+}
+
+// redrawHeatMap draws the number of requests in each latency bucket every second,
+// which shows the bands of latency like the cache misses hidden by the percentiles.
+func (d *drawer) redrawHeatMap(start, end time.Time) {
+	points, err := d.storage.SelectPoints(storage.LatencyMetricName, start, end)
+	if err != nil {
+		log.Printf("failed to select latency data points: %v\n", err)
+	}
+	highest := 0.0
+	for _, p := range points {
+		if p.Value > highest {
+			highest = p.Value
+		}
+	}
+	bounds := latencyBuckets(highest)
+	totals := countPerSecond(points, start, end)
+	first := firstNonZero(totals)
+
+	values := make([][]float64, len(bounds))
+	for i := range values {
+		values[i] = make([]float64, len(totals)-first)
+	}
+	for _, p := range points {
+		offset := p.Timestamp - start.UnixNano()
+		if offset < 0 {
+			continue
+		}
+		x := int(offset/int64(time.Second)) - first
+		if x < 0 || x >= len(totals)-first {
+			continue
+		}
+		y := sort.Search(len(bounds), func(i int) bool { return bounds[i] > p.Value }) - 1
+		values[y][x]++
+	}
+
+	xLabels := make([]string, len(totals)-first)
+	for i := range xLabels {
+		xLabels[i] = fmt.Sprintf("%ds", i)
+	}
+	yLabels := make([]string, len(bounds))
+	for i, b := range bounds {
+		yLabels[i] = time.Duration(b * float64(time.Millisecond)).String()
+	}
+	d.widgets.latencyHeatMap.Values(xLabels, yLabels, values)
+}
+
+// latencyBuckets gives back the lower bounds in milliseconds of the buckets up to the given latency,
+// which are 0 and then 1, 2 and 5 times powers of ten.
+func latencyBuckets(highest float64) []float64 {
+	bounds := []float64{0}
+	for base := 1.0; ; base *= 10 {
+		for _, step := range []float64{1, 2, 5} {
+			b := base * step
+			if b > highest {
+				return bounds
+			}
+			bounds = append(bounds, b)
+		}
+	}
+}
+
+// countPerSecond gives back the number of the given data points in each second from start to end.
+func countPerSecond(points []storage.DataPoint, start, end time.Time) []float64 {
+	return perSecond(points, start, end, func(storage.DataPoint) float64 { return 1 })
+}
+
+// sumPerSecond gives back the sum of the values of the given data points in each second from start to end.
+func sumPerSecond(points []storage.DataPoint, start, end time.Time) []float64 {
+	return perSecond(points, start, end, func(p storage.DataPoint) float64 { return p.Value })
+}
+
+func perSecond(points []storage.DataPoint, start, end time.Time, value func(storage.DataPoint) float64) []float64 {
+	sums := make([]float64, int(end.Sub(start)/time.Second))
+	for _, p := range points {
+		offset := p.Timestamp - start.UnixNano()
+		if offset < 0 {
+			continue
+		}
+		if i := int(offset / int64(time.Second)); i < len(sums) {
+			sums[i] += value(p)
+		}
+	}
+	return sums
+}
+
+// firstNonZero gives back the first index where any of the given series of the same length isn't zero.
+// It gives back the length if all are zero.
+func firstNonZero(series ...[]float64) int {
+	if len(series) == 0 {
+		return 0
+	}
+	first := len(series[0])
+	for _, s := range series {
+		for i := 0; i < first && i < len(s); i++ {
+			if s[i] != 0 {
+				first = i
+				break
+			}
+		}
+	}
+	return first
+}
+
+func (d *drawer) now() time.Time {
+	if d.clock != nil {
+		return d.clock()
+	}
+	return time.Now()
+}
+
+// baselineSeries gives back a dashed horizontal line at the given value, as long as the given number of data points.
+// The line is broken by NaN values which aren't drawn.
+func baselineSeries(value float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		if i/2%2 == 0 {
+			values[i] = value
+		} else {
+			values[i] = math.NaN()
+		}
+	}
+	return values
+}
+
+// stageBoundaries gives back the labels to be put where each stage begins,
+// keyed by the index of the data points.
+func (d *drawer) stageBoundaries(stages []float64) map[int]string {
+	labels := make(map[int]string)
+	for i, s := range stages {
+		if i > 0 && stages[i-1] == s {
+			continue
+		}
+		idx := int(s) - 1
+		if idx < 0 || idx >= len(d.stageNames) {
+			continue
+		}
+		labels[i] = d.stageNames[idx]
+	}
+	return labels
+}
+
 func (d *drawer) redrawGauge(ctx context.Context, duration time.Duration) {
 	ticker := time.NewTicker(d.redrawInterval)
 	defer ticker.Stop()
 
 	totalTime := float64(duration)
 
-	d.widgets.progressGauge.Percent(0)
-	for start := time.Now(); ; {
+	// Clear the label and the color which can be left by the previous attack.
+	d.widgets.progressGauge.Percent(0, gauge.TextLabel(""), gauge.Color(gauge.DefaultColor))
+	var (
+		percent int
+		// passed is the time passed excluding the pauses.
+		passed time.Duration
+		last   = time.Now()
+	)
+	for {
 		select {
 		case <-ctx.Done():
+			var abortErr *attacker.AbortError
+			switch cause := context.Cause(ctx); {
+			case errors.As(cause, &abortErr):
+				d.widgets.progressGauge.Percent(percent,
+					gauge.TextLabel(abortErr.Error()),
+					gauge.Color(cell.ColorRed),
+				)
+			case errors.Is(cause, attacker.ErrStopped):
+				d.widgets.progressGauge.Percent(percent, gauge.TextLabel("stopped"))
+			}
 			return
-		case <-ticker.C:
-			passed := float64(time.Since(start))
-			percent := int(passed / totalTime * 100)
+		case now := <-ticker.C:
+			if d.paused.Load() {
+				last = now
+				d.widgets.progressGauge.Percent(percent, gauge.TextLabel("paused"))
+				continue
+			}
+			passed += now.Sub(last)
+			last = now
+			p := int(float64(passed) / totalTime * 100)
 			// as time.Duration is the unit of nanoseconds
 			// small duration can exceed 100 on slow machines
-			if percent > 100 {
+			if p > 100 {
 				continue
 			}
-			d.widgets.progressGauge.Percent(percent)
+			percent = p
+			d.widgets.progressGauge.Percent(percent, gauge.TextLabel(""))
+		}
+	}
+}
+
+func (d *drawer) setCancelAttack(cancel context.CancelCauseFunc) {
+	d.cancelMu.Lock()
+	defer d.cancelMu.Unlock()
+	d.cancelAttack = cancel
+}
+
+// stopAttack stops the ongoing attack if any, so that its results get finalized.
+func (d *drawer) stopAttack() {
+	d.cancelMu.Lock()
+	defer d.cancelMu.Unlock()
+	if d.cancelAttack != nil {
+		d.cancelAttack(attacker.ErrStopped)
+	}
+}
+
+// redrawParams keeps the parameters up-to-date, at the specified interval as redrawInterval.
+// It aims to show the rate that changes over time according to the load profile.
+func (d *drawer) redrawParams(ctx context.Context, a attacker.Attacker) {
+	ticker := time.NewTicker(d.redrawInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.widgets.paramsText.Write(makeParamsText(d.targetURL, a), text.WriteReplace())
+			return
+		case <-ticker.C:
+			d.widgets.paramsText.Write(makeParamsText(d.targetURL, a), text.WriteReplace())
 		}
 	}
 }
 
 const (
 	latenciesTextFormat = `Total: %v
-Mean: %v
-P50: %v
-P90: %v
-P95: %v
-P99: %v
-Max: %v
-Min: %v`
+Mean: %v%s
+P50: %v%s
+P90: %v%s
+P95: %v%s
+P99: %v%s
+Max: %v%s
+Min: %v%s`
 
 	bytesTextFormat = `In:
   Total: %v
@@ -150,6 +504,16 @@ Success: %f
 Earliest: %v
 Latest: %v
 End: %v`
+
+	targetTextFormat = `%s %s
+  Requests: %d
+  Success: %.2f%%
+  P50: %v
+  P99: %v
+`
+
+	checkTextFormat = `%s %s (actual: %s)
+`
 )
 
 // redrawMetrics writes the metrics held by itself into the widgets, at the specified interval as redrawInterval.
@@ -165,17 +529,21 @@ func (d *drawer) redrawMetrics(ctx context.Context) {
 			d.mu.RLock()
 			m := *d.metrics
 			d.mu.RUnlock()
+			var base export.LatencySummary
+			if d.baseline != nil {
+				base = d.baseline.Summary.LatencyMS
+			}
 
 			d.widgets.latenciesText.Write(
 				fmt.Sprintf(latenciesTextFormat,
 					m.Latencies.Total,
-					m.Latencies.Mean,
-					m.Latencies.P50,
-					m.Latencies.P90,
-					m.Latencies.P95,
-					m.Latencies.P99,
-					m.Latencies.Max,
-					m.Latencies.Min,
+					m.Latencies.Mean, d.latencyDelta(m.Latencies.Mean, base.Mean),
+					m.Latencies.P50, d.latencyDelta(m.Latencies.P50, base.P50),
+					m.Latencies.P90, d.latencyDelta(m.Latencies.P90, base.P90),
+					m.Latencies.P95, d.latencyDelta(m.Latencies.P95, base.P95),
+					m.Latencies.P99, d.latencyDelta(m.Latencies.P99, base.P99),
+					m.Latencies.Max, d.latencyDelta(m.Latencies.Max, base.Max),
+					m.Latencies.Min, d.latencyDelta(m.Latencies.Min, base.Min),
 				), text.WriteReplace())
 
 			d.widgets.bytesText.Write(
@@ -218,8 +586,84 @@ func (d *drawer) redrawMetrics(ctx context.Context) {
 `, e)
 			}
 			d.widgets.errorsText.Write(errorsText, text.WriteReplace())
+
+			targetsText := ""
+			for _, t := range m.Targets {
+				targetsText += fmt.Sprintf(targetTextFormat,
+					t.Method,
+					t.URL,
+					t.Requests,
+					t.Success*100,
+					t.Latencies.P50,
+					t.Latencies.P99,
+				)
+			}
+			d.widgets.targetsText.Write(targetsText, text.WriteReplace())
+
+			if len(m.Histogram) > 0 {
+				d.redrawHistogram(m.Histogram)
+			}
+
+			d.widgets.checksText.Write("", text.WriteReplace())
+			for _, c := range m.Checks {
+				verdict, color := "PASS", cell.ColorGreen
+				if !c.Passed {
+					verdict, color = "FAIL", cell.ColorRed
+				}
+				d.widgets.checksText.Write(fmt.Sprintf(checkTextFormat, verdict, c.Assertion, c.Actual),
+					text.WriteCellOpts(cell.FgColor(color)))
+			}
+		}
+	}
+}
+
+// redrawHistogram draws a bar for each bucket, labeled with its range like "10ms-50ms" or "100ms+".
+func (d *drawer) redrawHistogram(buckets []attacker.HistogramBucket) {
+	values := make([]int, len(buckets))
+	labels := make([]string, len(buckets))
+	barColors := make([]cell.Color, len(buckets))
+	valueColors := make([]cell.Color, len(buckets))
+	labelColors := make([]cell.Color, len(buckets))
+	// The maximum has to be at least 1 even if no response has come in yet.
+	highest := 1
+	for i, b := range buckets {
+		values[i] = int(b.Count)
+		if values[i] > highest {
+			highest = values[i]
+		}
+		if i+1 < len(buckets) {
+			labels[i] = fmt.Sprintf("%s-%s", b.Min, b.Max)
+		} else {
+			labels[i] = fmt.Sprintf("%s+", b.Min)
 		}
+		barColors[i] = cell.ColorNumber(87)
+		valueColors[i] = cell.ColorBlack
+		labelColors[i] = cell.ColorGreen
+	}
+	d.widgets.histogramChart.Values(values, highest,
+		barchart.Labels(labels),
+		barchart.BarColors(barColors),
+		barchart.ValueColors(valueColors),
+		barchart.LabelColors(labelColors),
+	)
+}
+
+// latencyDelta gives back the difference of the given latency from the baseline one, like " (+1.2ms, +10.5%)".
+// It gives back an empty string if no baseline is given.
+func (d *drawer) latencyDelta(current time.Duration, baselineMS float64) string {
+	if d.baseline == nil {
+		return ""
+	}
+	baseline := time.Duration(baselineMS * float64(time.Millisecond))
+	diff := current - baseline
+	sign := ""
+	if diff >= 0 {
+		sign = "+"
+	}
+	if baseline == 0 {
+		return fmt.Sprintf(" (%s%v)", sign, diff)
 	}
+	return fmt.Sprintf(" (%s%v, %s%.1f%%)", sign, diff, sign, float64(diff)/float64(baseline)*100)
 }
 
 func (d *drawer) updateMetrics(ctx context.Context) {
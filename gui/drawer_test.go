@@ -2,13 +2,16 @@ package gui
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
 
 	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
 	"github.com/nakabonne/ali/storage"
 )
 
@@ -47,15 +50,389 @@ func TestRedrawCharts(t *testing.T) {
 			defer cancel()
 			d := &drawer{
 				redrawInterval: DefaultRedrawInterval,
-				widgets:        &widgets{latencyChart: tt.latencyChart, percentilesChart: tt.percentilesChart},
-				chartDrawing:   atomic.NewBool(false),
-				storage:        tt.storage,
+				widgets: &widgets{
+					latencyChart:     tt.latencyChart,
+					percentilesChart: tt.percentilesChart,
+					statusChart: func() LineChart {
+						l := NewMockLineChart(ctrl)
+						l.EXPECT().Series(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+						return l
+					}(),
+					statusLegends: make([]chartLegend, len(storage.StatusClasses)),
+					errorCountChart: func() LineChart {
+						l := NewMockLineChart(ctrl)
+						l.EXPECT().Series(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+						return l
+					}(),
+					errorRatioChart: func() LineChart {
+						l := NewMockLineChart(ctrl)
+						l.EXPECT().Series(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+						return l
+					}(),
+					rateChart: func() LineChart {
+						l := NewMockLineChart(ctrl)
+						l.EXPECT().Series(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+						return l
+					}(),
+					latencyHeatMap: func() HeatMap {
+						h := NewMockHeatMap(ctrl)
+						h.EXPECT().Values(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+						return h
+					}(),
+				},
+				chartDrawing: atomic.NewBool(false),
+				storage:      tt.storage,
 			}
 			go d.redrawCharts(ctx)
 		})
 	}
 }
 
+func TestRedrawStatusChart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name    string
+		storage storage.Reader
+		want    map[string][]float64
+	}{
+		{
+			name:    "no data points",
+			storage: &storage.FakeStorage{},
+			want: map[string][]float64{
+				"1xx": {}, "2xx": {}, "3xx": {}, "4xx": {}, "5xx": {}, storage.ClassNoResponse: {},
+			},
+		},
+		{
+			// The fake storage gives back the same data points at 0s, 1s and 2s for every class.
+			name:    "stacked from the first response",
+			storage: &storage.FakeStorage{Values: []float64{1, 1, 1}},
+			want: map[string][]float64{
+				"1xx":                   {1, 1, 1, 0, 0},
+				"2xx":                   {2, 2, 2, 0, 0},
+				"3xx":                   {3, 3, 3, 0, 0},
+				"4xx":                   {4, 4, 4, 0, 0},
+				"5xx":                   {5, 5, 5, 0, 0},
+				storage.ClassNoResponse: {6, 6, 6, 0, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := NewMockLineChart(ctrl)
+			for class, values := range tt.want {
+				chart.EXPECT().Series(class, values, gomock.Any())
+			}
+			d := &drawer{
+				widgets: &widgets{
+					statusChart:   chart,
+					statusLegends: make([]chartLegend, len(storage.StatusClasses)),
+				},
+				storage: tt.storage,
+			}
+			d.redrawStatusChart(start, start.Add(5*time.Second))
+		})
+	}
+}
+
+func TestRedrawErrorCharts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name       string
+		storage    storage.Reader
+		wantCounts []float64
+		wantRatios []float64
+	}{
+		{
+			name:       "no data points",
+			storage:    &storage.FakeStorage{},
+			wantCounts: []float64{},
+			wantRatios: []float64{},
+		},
+		{
+			// The fake storage gives back the data points at 0s, 1s and 2s.
+			name:       "failed every other second",
+			storage:    &storage.FakeStorage{Values: []float64{1, 0, 1}},
+			wantCounts: []float64{1, 0, 1},
+			wantRatios: []float64{100, 0, 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			countChart := NewMockLineChart(ctrl)
+			countChart.EXPECT().Series("errors", tt.wantCounts, gomock.Any())
+			ratioChart := NewMockLineChart(ctrl)
+			ratioChart.EXPECT().Series("error ratio", tt.wantRatios, gomock.Any())
+			d := &drawer{
+				widgets: &widgets{
+					errorCountChart: countChart,
+					errorRatioChart: ratioChart,
+				},
+				storage: tt.storage,
+			}
+			d.redrawErrorCharts(start, start.Add(3*time.Second))
+		})
+	}
+}
+
+func TestRedrawRateChart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name    string
+		storage storage.Reader
+		want    map[string][]float64
+	}{
+		{
+			name:    "no data points",
+			storage: &storage.FakeStorage{},
+			want: map[string][]float64{
+				"rate":       {},
+				"throughput": {},
+			},
+		},
+		{
+			// The fake storage gives back the same data points at 0s, 1s and 2s for every metric.
+			name:    "target filled forward",
+			storage: &storage.FakeStorage{Values: []float64{5, 0, 5}},
+			want: map[string][]float64{
+				"target":     {5, 0, 5, 5},
+				"rate":       {1, 1, 1, 0},
+				"throughput": {5, 0, 5, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := NewMockLineChart(ctrl)
+			for name, values := range tt.want {
+				chart.EXPECT().Series(name, values, gomock.Any())
+			}
+			d := &drawer{
+				widgets: &widgets{
+					rateChart: chart,
+				},
+				storage: tt.storage,
+			}
+			d.redrawRateChart(start, start.Add(4*time.Second))
+		})
+	}
+}
+
+func TestRedrawHistogram(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name       string
+		buckets    []attacker.HistogramBucket
+		wantValues []int
+		wantMax    int
+	}{
+		{
+			name: "no response yet",
+			buckets: []attacker.HistogramBucket{
+				{Min: 0, Max: 10 * time.Millisecond},
+				{Min: 10 * time.Millisecond},
+			},
+			wantValues: []int{0, 0},
+			wantMax:    1,
+		},
+		{
+			name: "highest bucket as the maximum",
+			buckets: []attacker.HistogramBucket{
+				{Min: 0, Max: 10 * time.Millisecond, Count: 1},
+				{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Count: 5},
+				{Min: 100 * time.Millisecond, Count: 2},
+			},
+			wantValues: []int{1, 5, 2},
+			wantMax:    5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := NewMockBarChart(ctrl)
+			chart.EXPECT().Values(tt.wantValues, tt.wantMax, gomock.Any())
+			d := &drawer{
+				widgets: &widgets{histogramChart: chart},
+			}
+			d.redrawHistogram(tt.buckets)
+		})
+	}
+}
+
+func TestRedrawHeatMap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name        string
+		storage     storage.Reader
+		wantXLabels []string
+		wantYLabels []string
+		wantValues  [][]float64
+	}{
+		{
+			name:        "no data points",
+			storage:     &storage.FakeStorage{},
+			wantXLabels: []string{},
+			wantYLabels: []string{"0s"},
+			wantValues:  [][]float64{{}},
+		},
+		{
+			// The fake storage gives back the latencies in milliseconds at 0s, 1s and 2s.
+			name:        "bucketed by latency",
+			storage:     &storage.FakeStorage{Values: []float64{1, 3, 450}},
+			wantXLabels: []string{"0s", "1s", "2s"},
+			wantYLabels: []string{"0s", "1ms", "2ms", "5ms", "10ms", "20ms", "50ms", "100ms", "200ms"},
+			wantValues: [][]float64{
+				{0, 0, 0},
+				{1, 0, 0},
+				{0, 1, 0},
+				{0, 0, 0},
+				{0, 0, 0},
+				{0, 0, 0},
+				{0, 0, 0},
+				{0, 0, 0},
+				{0, 0, 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heatMap := NewMockHeatMap(ctrl)
+			heatMap.EXPECT().Values(tt.wantXLabels, tt.wantYLabels, tt.wantValues)
+			d := &drawer{
+				widgets: &widgets{latencyHeatMap: heatMap},
+				storage: tt.storage,
+			}
+			d.redrawHeatMap(start, start.Add(3*time.Second))
+		})
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		highest float64
+		want    []float64
+	}{
+		{
+			name:    "below 1ms",
+			highest: 0,
+			want:    []float64{0},
+		},
+		{
+			name:    "on a bound",
+			highest: 20,
+			want:    []float64{0, 1, 2, 5, 10, 20},
+		},
+		{
+			name:    "over a second",
+			highest: 1500,
+			want:    []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, latencyBuckets(tt.highest))
+		})
+	}
+}
+
+func TestCountPerSecond(t *testing.T) {
+	start := time.Unix(100, 0)
+	points := []storage.DataPoint{
+		{Timestamp: start.Add(-time.Millisecond).UnixNano()},
+		{Timestamp: start.UnixNano()},
+		{Timestamp: start.Add(999 * time.Millisecond).UnixNano()},
+		{Timestamp: start.Add(2500 * time.Millisecond).UnixNano()},
+		{Timestamp: start.Add(3 * time.Second).UnixNano()},
+	}
+	assert.Equal(t, []float64{2, 0, 1}, countPerSecond(points, start, start.Add(3*time.Second)))
+}
+
+func TestSumPerSecond(t *testing.T) {
+	start := time.Unix(100, 0)
+	points := []storage.DataPoint{
+		{Timestamp: start.UnixNano(), Value: 1},
+		{Timestamp: start.Add(500 * time.Millisecond).UnixNano(), Value: 0},
+		{Timestamp: start.Add(1500 * time.Millisecond).UnixNano(), Value: 1},
+		{Timestamp: start.Add(1600 * time.Millisecond).UnixNano(), Value: 1},
+	}
+	assert.Equal(t, []float64{1, 2}, sumPerSecond(points, start, start.Add(2*time.Second)))
+}
+
+func TestFirstNonZero(t *testing.T) {
+	tests := []struct {
+		name   string
+		series [][]float64
+		want   int
+	}{
+		{
+			name: "no series",
+			want: 0,
+		},
+		{
+			name:   "all zero",
+			series: [][]float64{{0, 0}, {0, 0}},
+			want:   2,
+		},
+		{
+			name:   "earliest across series",
+			series: [][]float64{{0, 0, 1}, {0, 3, 0}},
+			want:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, firstNonZero(tt.series...))
+		})
+	}
+}
+
+func TestStageBoundaries(t *testing.T) {
+	tests := []struct {
+		name       string
+		stageNames []string
+		stages     []float64
+		want       map[int]string
+	}{
+		{
+			name:       "no data points",
+			stageNames: []string{"warm-up", "hold"},
+			stages:     []float64{},
+			want:       map[int]string{},
+		},
+		{
+			name:       "boundary in the middle",
+			stageNames: []string{"warm-up", "hold", "cool-down"},
+			stages:     []float64{1, 1, 2, 2, 2, 3},
+			want:       map[int]string{0: "warm-up", 2: "hold", 5: "cool-down"},
+		},
+		{
+			name:       "unknown stage",
+			stageNames: []string{"warm-up"},
+			stages:     []float64{1, 2},
+			want:       map[int]string{0: "warm-up"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &drawer{stageNames: tt.stageNames}
+			assert.Equal(t, tt.want, d.stageBoundaries(tt.stages))
+		})
+	}
+}
+
 func TestRedrawGauge(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -104,6 +481,8 @@ func TestUpdateMetrics(t *testing.T) {
 		othersText      Text
 		statusCodesText Text
 		errorsText      Text
+		targetsText     Text
+		checksText      Text
 	}{
 		{
 			name: "with errors",
@@ -137,6 +516,19 @@ func TestUpdateMetrics(t *testing.T) {
 				Success:     1,
 				StatusCodes: map[string]int{"200": 2},
 				Errors:      []string{"error1"},
+				Targets: []attacker.TargetMetrics{
+					{
+						Method:    "GET",
+						URL:       "http://host.xz/a",
+						Latencies: attacker.LatencyMetrics{P50: 1, P99: 2},
+						Requests:  1,
+						Success:   1,
+					},
+				},
+				Checks: []attacker.CheckResult{
+					{Assertion: "p99<250ms", Actual: "1ns", Passed: true},
+					{Assertion: "success>=0.999", Actual: "0.5", Passed: false},
+				},
 			},
 			latenciesText: func() Text {
 				t := NewMockText(ctrl)
@@ -176,6 +568,27 @@ Out:
 				return t
 			}(),
 
+			targetsText: func() Text {
+				t := NewMockText(ctrl)
+				t.EXPECT().Write(`GET http://host.xz/a
+  Requests: 1
+  Success: 100.00%
+  P50: 1ns
+  P99: 2ns
+`, gomock.Any()).AnyTimes()
+				return t
+			}(),
+
+			checksText: func() Text {
+				t := NewMockText(ctrl)
+				t.EXPECT().Write("", gomock.Any()).AnyTimes()
+				t.EXPECT().Write(`PASS p99<250ms (actual: 1ns)
+`, gomock.Any()).AnyTimes()
+				t.EXPECT().Write(`FAIL success>=0.999 (actual: 0.5)
+`, gomock.Any()).AnyTimes()
+				return t
+			}(),
+
 			othersText: func() Text {
 				t := NewMockText(ctrl)
 				t.EXPECT().Write(`Duration: 1ns
@@ -204,6 +617,8 @@ End: 2009-11-10T23:00:00Z`, gomock.Any()).AnyTimes()
 					othersText:      tt.othersText,
 					statusCodesText: tt.statusCodesText,
 					errorsText:      tt.errorsText,
+					targetsText:     tt.targetsText,
+					checksText:      tt.checksText,
 				},
 				metrics: tt.metrics,
 			}
@@ -213,3 +628,71 @@ End: 2009-11-10T23:00:00Z`, gomock.Any()).AnyTimes()
 		})
 	}
 }
+
+func TestStopAttack(t *testing.T) {
+	d := &drawer{}
+	// No attack has been started yet.
+	d.stopAttack()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	d.setCancelAttack(cancel)
+	d.stopAttack()
+	assert.ErrorIs(t, context.Cause(ctx), attacker.ErrStopped)
+}
+
+func TestBaselineSeries(t *testing.T) {
+	got := baselineSeries(1.5, 6)
+	assert.Len(t, got, 6)
+	for i, v := range got {
+		if i == 0 || i == 1 || i == 4 || i == 5 {
+			assert.Equal(t, 1.5, v)
+		} else {
+			assert.True(t, math.IsNaN(v))
+		}
+	}
+}
+
+func TestLatencyDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseline   *export.Baseline
+		current    time.Duration
+		baselineMS float64
+		want       string
+	}{
+		{
+			name:       "no baseline",
+			current:    time.Millisecond,
+			baselineMS: 1,
+			want:       "",
+		},
+		{
+			name:       "slower",
+			baseline:   &export.Baseline{},
+			current:    12 * time.Millisecond,
+			baselineMS: 10,
+			want:       " (+2ms, +20.0%)",
+		},
+		{
+			name:       "faster",
+			baseline:   &export.Baseline{},
+			current:    5 * time.Millisecond,
+			baselineMS: 10,
+			want:       " (-5ms, -50.0%)",
+		},
+		{
+			name:       "zero baseline",
+			baseline:   &export.Baseline{},
+			current:    time.Millisecond,
+			baselineMS: 0,
+			want:       " (+1ms)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &drawer{baseline: tt.baseline}
+			assert.Equal(t, tt.want, d.latencyDelta(tt.current, tt.baselineMS))
+		})
+	}
+}
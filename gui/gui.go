@@ -16,6 +16,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
 	"github.com/nakabonne/ali/storage"
 )
 
@@ -30,6 +31,10 @@ const (
 type Options struct {
 	RedrawInternal time.Duration
 	QueryRange     time.Duration
+	// AbortConditions are watched while attacking, to abort the attack once any of them is met.
+	AbortConditions []attacker.AbortCondition
+	// Baseline is overlaid on the percentiles chart and compared in the Latencies panel if given.
+	Baseline *export.Baseline
 }
 
 type runner func(ctx context.Context, t terminalapi.Terminal, c *container.Container, opts ...termdash.Option) error
@@ -60,7 +65,7 @@ func run(t terminalapi.Terminal, r runner, targetURL string, storage storage.Rea
 		return fmt.Errorf("failed to generate container: %w", err)
 	}
 
-	w, err := newWidgets(targetURL, a.Rate(), a.Duration(), a.Method())
+	w, err := newWidgets(makeParamsText(targetURL, a))
 	if err != nil {
 		return fmt.Errorf("failed to generate widgets: %w", err)
 	}
@@ -81,7 +86,13 @@ func run(t terminalapi.Terminal, r runner, targetURL string, storage storage.Rea
 		return fmt.Errorf("redrawInterval must be greater than %s", minRedrawInterval)
 	}
 
+	stageNames := make([]string, 0, len(a.Stages()))
+	for _, s := range a.Stages() {
+		stageNames = append(stageNames, s.Name)
+	}
 	d := &drawer{
+		targetURL:      targetURL,
+		stageNames:     stageNames,
 		queryRange:     opts.QueryRange,
 		redrawInterval: opts.RedrawInternal,
 		widgets:        w,
@@ -90,6 +101,9 @@ func run(t terminalapi.Terminal, r runner, targetURL string, storage storage.Rea
 		chartDrawing:   atomic.NewBool(false),
 		metrics:        &attacker.Metrics{},
 		storage:        storage,
+
+		abortConditions: opts.AbortConditions,
+		baseline:        opts.Baseline,
 	}
 	go d.updateMetrics(ctx)
 	go d.redrawMetrics(ctx)
@@ -138,6 +152,11 @@ type gridOpts struct {
 	// so we can replace containers
 	latency     []container.Option
 	percentiles []container.Option
+	statusCodes []container.Option
+	errors      []container.Option
+	rates       []container.Option
+	histogram   []container.Option
+	heatMap     []container.Option
 }
 
 func gridLayout(w *widgets) (*gridOpts, error) {
@@ -146,14 +165,18 @@ func gridLayout(w *widgets) (*gridOpts, error) {
 		grid.Widget(w.latencyChart, container.Border(linestyle.Light), container.BorderTitle("Latency (ms)")),
 	)
 	raw2 := grid.RowHeightPerc(25,
-		grid.ColWidthPerc(20, grid.Widget(w.paramsText, container.Border(linestyle.Light), container.BorderTitle("Parameters"))),
-		grid.ColWidthPerc(20, grid.Widget(w.latenciesText, container.Border(linestyle.Light), container.BorderTitle("Latencies"))),
-		grid.ColWidthPerc(20, grid.Widget(w.bytesText, container.Border(linestyle.Light), container.BorderTitle("Bytes"))),
-		grid.ColWidthPerc(20,
+		grid.ColWidthPerc(15, grid.Widget(w.paramsText, container.Border(linestyle.Light), container.BorderTitle("Parameters"))),
+		grid.ColWidthPerc(15, grid.Widget(w.latenciesText, container.Border(linestyle.Light), container.BorderTitle("Latencies"))),
+		grid.ColWidthPerc(15, grid.Widget(w.bytesText, container.Border(linestyle.Light), container.BorderTitle("Bytes"))),
+		grid.ColWidthPerc(15,
 			grid.RowHeightPerc(50, grid.Widget(w.statusCodesText, container.Border(linestyle.Light), container.BorderTitle("Status Codes"))),
 			grid.RowHeightPerc(50, grid.Widget(w.errorsText, container.Border(linestyle.Light), container.BorderTitle("Errors"))),
 		),
 		grid.ColWidthPerc(20, grid.Widget(w.othersText, container.Border(linestyle.Light), container.BorderTitle("Others"))),
+		grid.ColWidthPerc(20,
+			grid.RowHeightPerc(50, grid.Widget(w.targetsText, container.Border(linestyle.Light), container.BorderTitle("Targets"))),
+			grid.RowHeightPerc(50, grid.Widget(w.checksText, container.Border(linestyle.Light), container.BorderTitle("Checks"))),
+		),
 	)
 	raw3 := grid.RowHeightPerc(4,
 		grid.ColWidthPerc(60, grid.Widget(w.progressGauge, container.Border(linestyle.Light), container.BorderTitle("Progress"))),
@@ -187,9 +210,67 @@ func gridLayout(w *widgets) (*gridOpts, error) {
 		return nil, err
 	}
 
+	statusLegends := make([]Text, 0, len(w.statusLegends))
+	for _, l := range w.statusLegends {
+		statusLegends = append(statusLegends, l.text)
+	}
+	statusCodesOpts, err := newChartWithLegends(w.statusChart, []container.Option{
+		container.Border(linestyle.Light),
+		container.ID(chartID),
+		container.BorderTitle("Responses per second by status class (stacked)"),
+	}, statusLegends...)
+	if err != nil {
+		return nil, err
+	}
+
+	errorsBuilder := grid.New()
+	errorsBuilder.Add(grid.RowHeightPercWithOpts(70,
+		[]container.Option{container.ID(chartID)},
+		grid.RowHeightPerc(50, grid.Widget(w.errorCountChart, container.Border(linestyle.Light), container.BorderTitle("Errors per second"))),
+		grid.RowHeightPerc(50, grid.Widget(w.errorRatioChart, container.Border(linestyle.Light), container.BorderTitle("Error ratio (%)"))),
+	))
+	errorsOpts, err := errorsBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ratesOpts, err := newChartWithLegends(w.rateChart, []container.Option{
+		container.Border(linestyle.Light),
+		container.ID(chartID),
+		container.BorderTitle("Rate and throughput (req/s)"),
+	}, w.targetRateLegend.text, w.rateLegend.text, w.throughputLegend.text)
+	if err != nil {
+		return nil, err
+	}
+
+	histogramBuilder := grid.New()
+	histogramBuilder.Add(grid.RowHeightPercWithOpts(70,
+		[]container.Option{container.ID(chartID)},
+		grid.Widget(w.histogramChart, container.Border(linestyle.Light), container.BorderTitle("Latency histogram (responses per bucket)")),
+	))
+	histogramOpts, err := histogramBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	heatMapBuilder := grid.New()
+	heatMapBuilder.Add(grid.RowHeightPercWithOpts(70,
+		[]container.Option{container.ID(chartID)},
+		grid.Widget(w.latencyHeatMap, container.Border(linestyle.Light), container.BorderTitle("Latency heatmap (requests per second in each latency bucket, green: fewer, red: more)")),
+	))
+	heatMapOpts, err := heatMapBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
 	return &gridOpts{
 		latency:     latencyOpts,
 		percentiles: percentilesOpts,
+		statusCodes: statusCodesOpts,
+		errors:      errorsOpts,
+		rates:       ratesOpts,
+		histogram:   histogramOpts,
+		heatMap:     heatMapOpts,
 		base:        baseOpts,
 	}, nil
 }
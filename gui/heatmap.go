@@ -0,0 +1,192 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// heatMapColors are the colors of the cells, from the fewest to the most.
+var heatMapColors = []cell.Color{
+	cell.ColorNumber(22),
+	cell.ColorNumber(28),
+	cell.ColorNumber(34),
+	cell.ColorNumber(40),
+	cell.ColorNumber(46),
+	cell.ColorNumber(82),
+	cell.ColorNumber(118),
+	cell.ColorNumber(154),
+	cell.ColorNumber(190),
+	cell.ColorNumber(226),
+	cell.ColorNumber(220),
+	cell.ColorNumber(214),
+	cell.ColorNumber(208),
+	cell.ColorNumber(202),
+	cell.ColorNumber(196),
+}
+
+// heatMap draws the values as a grid of cells colored by how large they are, leaving the zero ones blank.
+// It's here because the heatmap widget of termdash isn't implemented yet.
+type heatMap struct {
+	// xLabels and yLabels are in increasing order, so the first row is drawn at the bottom.
+	xLabels []string
+	yLabels []string
+	// values are indexed by the row and then the column.
+	values    [][]float64
+	labelOpts []cell.Option
+	mu        sync.RWMutex
+}
+
+func newHeatMap(labelOpts ...cell.Option) *heatMap {
+	return &heatMap{labelOpts: labelOpts}
+}
+
+// Values sets the values to be drawn, where len(values) == len(yLabels) and len(values[i]) == len(xLabels).
+func (h *heatMap) Values(xLabels, yLabels []string, values [][]float64) error {
+	if len(values) != len(yLabels) {
+		return fmt.Errorf("%d rows given for %d Y labels", len(values), len(yLabels))
+	}
+	copied := make([][]float64, len(values))
+	for i, row := range values {
+		if len(row) != len(xLabels) {
+			return fmt.Errorf("%d values given in row %d for %d X labels", len(row), i, len(xLabels))
+		}
+		copied[i] = make([]float64, len(row))
+		copy(copied[i], row)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.xLabels = append([]string(nil), xLabels...)
+	h.yLabels = append([]string(nil), yLabels...)
+	h.values = copied
+	return nil
+}
+
+// Draw draws the Y labels on the left, the X labels at the bottom and the cells in the rest.
+// Every row has the same height and every column has the same width. If all don't fit, only the highest rows
+// are drawn so that the tail latency stays visible, and only the latest columns.
+func (h *heatMap) Draw(cvs *canvas.Canvas, _ *widgetapi.Meta) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.yLabels) == 0 || len(h.xLabels) == 0 {
+		return nil
+	}
+	ar := cvs.Area()
+	labelWidth := h.yLabelWidth() + 1
+	width, height := ar.Dx()-labelWidth, ar.Dy()-1
+	if width < 1 || height < 1 {
+		return nil
+	}
+
+	rowHeight := height / len(h.yLabels)
+	if rowHeight < 1 {
+		rowHeight = 1
+	}
+	rows := len(h.yLabels)
+	if rows > height {
+		rows = height
+	}
+	firstRow := len(h.yLabels) - rows
+	colWidth := width / len(h.xLabels)
+	if colWidth < 1 {
+		colWidth = 1
+	}
+	cols := len(h.xLabels)
+	if cols > width {
+		cols = width
+	}
+	firstCol := len(h.xLabels) - cols
+
+	highest := 0.0
+	for _, row := range h.values {
+		for _, v := range row {
+			if v > highest {
+				highest = v
+			}
+		}
+	}
+
+	for y := 0; y < rows; y++ {
+		bottom := height - y*rowHeight
+		if err := draw.Text(cvs, h.yLabels[firstRow+y], image.Point{X: 0, Y: bottom - 1}, draw.TextCellOpts(h.labelOpts...), draw.TextMaxX(labelWidth-1)); err != nil {
+			return err
+		}
+		for x := 0; x < cols; x++ {
+			v := h.values[firstRow+y][firstCol+x]
+			if v <= 0 {
+				continue
+			}
+			left := labelWidth + x*colWidth
+			cellArea := image.Rect(left, bottom-rowHeight, left+colWidth, bottom)
+			if err := cvs.SetAreaCells(cellArea, ' ', cell.BgColor(heatMapColor(v, highest))); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The X labels are drawn from the left, skipping the ones which would overlap with the previous one.
+	next := labelWidth
+	for x := 0; x < cols; x++ {
+		label := h.xLabels[firstCol+x]
+		left := labelWidth + x*colWidth
+		if label == "" || left < next {
+			continue
+		}
+		if left+utf8.RuneCountInString(label) > ar.Dx() {
+			break
+		}
+		if err := draw.Text(cvs, label, image.Point{X: left, Y: height}, draw.TextCellOpts(h.labelOpts...)); err != nil {
+			return err
+		}
+		next = left + utf8.RuneCountInString(label) + 1
+	}
+	return nil
+}
+
+// yLabelWidth gives back the width of the longest Y label. h.mu must be held.
+func (h *heatMap) yLabelWidth() int {
+	width := 0
+	for _, l := range h.yLabels {
+		if w := utf8.RuneCountInString(l); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// heatMapColor gives back the color of the given positive value relative to the highest one.
+func heatMapColor(value, highest float64) cell.Color {
+	i := int(value / highest * float64(len(heatMapColors)-1))
+	if i >= len(heatMapColors) {
+		i = len(heatMapColors) - 1
+	}
+	return heatMapColors[i]
+}
+
+// Keyboard input isn't supported on the heat map.
+func (*heatMap) Keyboard(_ *terminalapi.Keyboard, _ *widgetapi.EventMeta) error {
+	return errors.New("the heat map doesn't support keyboard events")
+}
+
+// Mouse input isn't supported on the heat map.
+func (*heatMap) Mouse(_ *terminalapi.Mouse, _ *widgetapi.EventMeta) error {
+	return errors.New("the heat map doesn't support mouse events")
+}
+
+func (h *heatMap) Options() widgetapi.Options {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return widgetapi.Options{
+		MinimumSize: image.Point{X: h.yLabelWidth() + 2, Y: 2},
+	}
+}
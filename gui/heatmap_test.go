@@ -0,0 +1,130 @@
+package gui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatMapValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		xLabels []string
+		yLabels []string
+		values  [][]float64
+		wantErr bool
+	}{
+		{
+			name:    "matched",
+			xLabels: []string{"0s", "1s"},
+			yLabels: []string{"0s"},
+			values:  [][]float64{{1, 2}},
+		},
+		{
+			name:    "too few rows",
+			xLabels: []string{"0s"},
+			yLabels: []string{"0s", "1ms"},
+			values:  [][]float64{{1}},
+			wantErr: true,
+		},
+		{
+			name:    "too many columns",
+			xLabels: []string{"0s"},
+			yLabels: []string{"0s"},
+			values:  [][]float64{{1, 2}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newHeatMap().Values(tt.xLabels, tt.yLabels, tt.values)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestHeatMapDraw(t *testing.T) {
+	h := newHeatMap()
+	require.NoError(t, h.Values([]string{"0s", "1s"}, []string{"0s", "1ms"}, [][]float64{
+		{1, 0},
+		{0, 2},
+	}))
+	cvs, err := canvas.New(image.Rect(0, 0, 10, 4))
+	require.NoError(t, err)
+	require.NoError(t, h.Draw(cvs, nil))
+
+	runeAt := func(x, y int) rune {
+		c, err := cvs.Cell(image.Point{X: x, Y: y})
+		require.NoError(t, err)
+		return c.Rune
+	}
+	bgAt := func(x, y int) cell.Color {
+		c, err := cvs.Cell(image.Point{X: x, Y: y})
+		require.NoError(t, err)
+		return c.Opts.BgColor
+	}
+
+	// The first row is at the bottom, above the X labels.
+	assert.Equal(t, '0', runeAt(0, 2))
+	assert.Equal(t, '1', runeAt(0, 1))
+	assert.Equal(t, '0', runeAt(4, 3))
+	assert.Equal(t, '1', runeAt(7, 3))
+
+	// Each of the two columns is three cells wide.
+	for x := 4; x < 7; x++ {
+		assert.Equal(t, heatMapColor(1, 2), bgAt(x, 2))
+		assert.Equal(t, cell.ColorDefault, bgAt(x, 1))
+	}
+	for x := 7; x < 10; x++ {
+		assert.Equal(t, cell.ColorDefault, bgAt(x, 2))
+		assert.Equal(t, heatMapColors[len(heatMapColors)-1], bgAt(x, 1))
+	}
+}
+
+func TestHeatMapDrawLatestColumns(t *testing.T) {
+	h := newHeatMap()
+	require.NoError(t, h.Values([]string{"0s", "1s", "2s"}, []string{"0s"}, [][]float64{{1, 0, 1}}))
+	// Only two columns fit next to the label.
+	cvs, err := canvas.New(image.Rect(0, 0, 5, 2))
+	require.NoError(t, err)
+	require.NoError(t, h.Draw(cvs, nil))
+
+	c, err := cvs.Cell(image.Point{X: 3, Y: 0})
+	require.NoError(t, err)
+	assert.Equal(t, cell.ColorDefault, c.Opts.BgColor)
+	c, err = cvs.Cell(image.Point{X: 4, Y: 0})
+	require.NoError(t, err)
+	assert.Equal(t, heatMapColors[len(heatMapColors)-1], c.Opts.BgColor)
+}
+
+func TestHeatMapDrawHighestRows(t *testing.T) {
+	h := newHeatMap()
+	require.NoError(t, h.Values([]string{"0s"}, []string{"0s", "1ms", "2ms"}, [][]float64{{1}, {0}, {2}}))
+	// Only two rows fit above the X labels.
+	cvs, err := canvas.New(image.Rect(0, 0, 5, 3))
+	require.NoError(t, err)
+	require.NoError(t, h.Draw(cvs, nil))
+
+	label := func(y int) string {
+		var l []rune
+		for x := 0; x < 3; x++ {
+			c, err := cvs.Cell(image.Point{X: x, Y: y})
+			require.NoError(t, err)
+			l = append(l, c.Rune)
+		}
+		return string(l)
+	}
+	assert.Equal(t, "1ms", label(1))
+	assert.Equal(t, "2ms", label(0))
+
+	c, err := cvs.Cell(image.Point{X: 4, Y: 1})
+	require.NoError(t, err)
+	assert.Equal(t, cell.ColorDefault, c.Opts.BgColor)
+	c, err = cvs.Cell(image.Point{X: 4, Y: 0})
+	require.NoError(t, err)
+	assert.Equal(t, heatMapColors[len(heatMapColors)-1], c.Opts.BgColor)
+}
@@ -2,10 +2,15 @@ package gui
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
 
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/keyboard"
 	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
 
 	"github.com/nakabonne/ali/attacker"
 )
@@ -29,14 +34,41 @@ func keybinds(ctx context.Context, cancel context.CancelFunc, c *container.Conta
 	funcs := []func(){
 		func() { c.Update(chartID, dr.gridOpts.latency...) },
 		func() { c.Update(chartID, dr.gridOpts.percentiles...) },
+		func() { c.Update(chartID, dr.gridOpts.statusCodes...) },
+		func() { c.Update(chartID, dr.gridOpts.errors...) },
+		func() { c.Update(chartID, dr.gridOpts.rates...) },
+		func() { c.Update(chartID, dr.gridOpts.heatMap...) },
+	}
+	// The histogram is there only when the buckets are given.
+	if len(a.Buckets()) > 0 {
+		funcs = append(funcs, func() { c.Update(chartID, dr.gridOpts.histogram...) })
 	}
 	navigateFunc := navigateCharts(funcs)
+	prompt := &ratePrompt{}
 	return func(k *terminalapi.Keyboard) {
+		if k.Key == keyboard.KeyCtrlC {
+			cancel()
+			return
+		}
+		if prompt.active {
+			prompt.handle(k.Key, dr, a)
+			return
+		}
 		switch k.Key {
-		case keyboard.KeyCtrlC, 'q': // Quit
+		case 'q': // Quit
 			cancel()
 		case keyboard.KeyEnter: // Attack
 			attack(ctx, cancel, dr, a)
+		case 'S', 's', keyboard.KeyEsc: // Stop
+			dr.stopAttack()
+		case 'P', 'p': // Pause or resume
+			togglePause(dr, a)
+		case '+': // Increase rate
+			changeRate(dr, a, 1)
+		case '-': // Decrease rate
+			changeRate(dr, a, -1)
+		case 'R', 'r': // Input rate
+			prompt.open(dr)
 		case 'H', 'h': // backwards
 			navigateFunc(true)
 		case 'L', 'l': // forwards
@@ -49,18 +81,105 @@ func attack(ctx context.Context, cancelParent context.CancelFunc, d *drawer, a a
 	if d.chartDrawing.Load() {
 		return
 	}
-	child, cancelChild := context.WithCancel(ctx)
+	child, cancelChild := context.WithCancelCause(ctx)
+	d.paused.Store(false)
+	d.setCancelAttack(cancelChild)
 
 	// To initialize, run redrawChart on a per-attack basis.
 	go d.redrawCharts(child)
 	go d.redrawGauge(child, a.Duration())
+	go d.redrawParams(child, a)
+	go attacker.WatchAbortConditions(child, cancelChild, d.storage, d.abortConditions, attacker.DefaultAbortCheckInterval)
 	go func() {
 		if err := a.Attack(child, d.metricsCh); err != nil {
 			d.setExportErr(err)
-			cancelChild()
+			cancelChild(nil)
 			cancelParent()
 			return
 		}
-		cancelChild()
+		cancelChild(nil)
 	}()
 }
+
+// togglePause pauses the ongoing attack, or resumes it if paused.
+func togglePause(d *drawer, a attacker.Attacker) {
+	if !d.chartDrawing.Load() {
+		return
+	}
+	if a.Paused() {
+		a.Resume()
+		d.paused.Store(false)
+		return
+	}
+	a.Pause()
+	d.paused.Store(true)
+}
+
+// changeRate increases or decreases the rate of the ongoing attack by 10%, at least by 1.
+func changeRate(d *drawer, a attacker.Attacker, sign int) {
+	if !d.chartDrawing.Load() {
+		return
+	}
+	current := int(math.Round(a.TargetRate()))
+	step := current / 10
+	if step < 1 {
+		step = 1
+	}
+	setRate(d, a, current+sign*step)
+}
+
+func setRate(d *drawer, a attacker.Attacker, rate int) {
+	if rate < 1 {
+		rate = 1
+	}
+	if err := a.SetRate(rate); err != nil {
+		log.Printf("failed to set rate: %v\n", err)
+		return
+	}
+	d.widgets.paramsText.Write(makeParamsText(d.targetURL, a), text.WriteReplace())
+}
+
+// ratePrompt takes the keyboard input of an exact rate, which is shown in the navigation.
+type ratePrompt struct {
+	active bool
+	input  string
+}
+
+func (p *ratePrompt) open(d *drawer) {
+	if !d.chartDrawing.Load() {
+		return
+	}
+	p.active = true
+	p.input = ""
+	p.show(d)
+}
+
+func (p *ratePrompt) close(d *drawer) {
+	p.active = false
+	p.input = ""
+	d.widgets.navi.Write(naviText, text.WriteReplace())
+}
+
+func (p *ratePrompt) show(d *drawer) {
+	d.widgets.navi.Write(fmt.Sprintf(ratePromptFormat, p.input), text.WriteReplace())
+}
+
+func (p *ratePrompt) handle(key keyboard.Key, d *drawer, a attacker.Attacker) {
+	switch {
+	case key == keyboard.KeyEnter:
+		if rate, err := strconv.Atoi(p.input); err == nil && d.chartDrawing.Load() {
+			setRate(d, a, rate)
+		}
+		p.close(d)
+	case key == keyboard.KeyEsc:
+		p.close(d)
+	case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+		p.show(d)
+	case key >= '0' && key <= '9':
+		p.input += string(rune(key))
+		p.show(d)
+	}
+}
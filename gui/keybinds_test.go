@@ -8,6 +8,8 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/mum4k/termdash/keyboard"
 	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
 	"github.com/nakabonne/ali/attacker"
@@ -41,6 +43,98 @@ func TestKeybinds(t *testing.T) {
 	}
 }
 
+func TestTogglePause(t *testing.T) {
+	tests := []struct {
+		name      string
+		attacking bool
+		toggles   int
+		want      bool
+	}{
+		{
+			name:      "not attacking",
+			attacking: false,
+			toggles:   1,
+			want:      false,
+		},
+		{
+			name:      "pause",
+			attacking: true,
+			toggles:   1,
+			want:      true,
+		},
+		{
+			name:      "resume",
+			attacking: true,
+			toggles:   2,
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &drawer{chartDrawing: atomic.NewBool(tt.attacking)}
+			a := &attacker.FakeAttacker{}
+			for i := 0; i < tt.toggles; i++ {
+				togglePause(d, a)
+			}
+			assert.Equal(t, tt.want, a.Paused())
+			assert.Equal(t, tt.want, d.paused.Load())
+		})
+	}
+}
+
+func TestChangeRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	paramsText := NewMockText(ctrl)
+	paramsText.EXPECT().Write(gomock.Any(), gomock.Any()).AnyTimes()
+	d := &drawer{
+		chartDrawing: atomic.NewBool(true),
+		widgets:      &widgets{paramsText: paramsText},
+	}
+	a := &attacker.FakeAttacker{}
+	require.NoError(t, a.SetRate(50))
+
+	changeRate(d, a, 1)
+	assert.Equal(t, 55, a.Rate())
+	changeRate(d, a, -1)
+	assert.Equal(t, 50, a.Rate())
+
+	require.NoError(t, a.SetRate(1))
+	changeRate(d, a, -1)
+	assert.Equal(t, 1, a.Rate(), "rate shouldn't go below 1")
+}
+
+func TestRatePrompt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	navi := NewMockText(ctrl)
+	gomock.InOrder(
+		navi.EXPECT().Write("New rate: _ (Enter: apply, Esc: cancel)", gomock.Any()),
+		navi.EXPECT().Write("New rate: 1_ (Enter: apply, Esc: cancel)", gomock.Any()),
+		navi.EXPECT().Write("New rate: 12_ (Enter: apply, Esc: cancel)", gomock.Any()),
+		navi.EXPECT().Write("New rate: 1_ (Enter: apply, Esc: cancel)", gomock.Any()),
+		navi.EXPECT().Write("New rate: 10_ (Enter: apply, Esc: cancel)", gomock.Any()),
+		navi.EXPECT().Write(naviText, gomock.Any()),
+	)
+	paramsText := NewMockText(ctrl)
+	paramsText.EXPECT().Write(gomock.Any(), gomock.Any())
+	d := &drawer{
+		chartDrawing: atomic.NewBool(true),
+		widgets:      &widgets{navi: navi, paramsText: paramsText},
+	}
+	a := &attacker.FakeAttacker{}
+
+	p := &ratePrompt{}
+	p.open(d)
+	for _, k := range []keyboard.Key{'1', '2', keyboard.KeyBackspace2, 'x', '0', keyboard.KeyEnter} {
+		p.handle(k, d, a)
+	}
+	assert.False(t, p.active)
+	assert.Equal(t, 10, a.Rate())
+}
+
 func TestNavigateCharts(t *testing.T) {
 	type test struct {
 		name            string
@@ -0,0 +1,291 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/termbox"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/gauge"
+	"github.com/mum4k/termdash/widgets/text"
+	"go.uber.org/atomic"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/storage"
+)
+
+const (
+	// replaySeekStep is how far the timeline moves by a single seek.
+	replaySeekStep = 5 * time.Second
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 64
+
+	replayNaviText = "q: quit, Space: play/pause, ←/→: seek 5s, +/-: speed up/down, 0: rewind, l: next chart, h: prev chart"
+)
+
+type ReplayOptions struct {
+	RedrawInternal time.Duration
+	QueryRange     time.Duration
+	// Speed is the initial playback speed, where 1 means the original pace.
+	Speed float64
+}
+
+// Replay plays back the given results of an exported run on the dashboard, along the timeline of the run.
+// The results have to be sorted by timestamp, and inserted into the storage beforehand.
+func Replay(runID string, storage storage.Reader, results []export.Result, opts ReplayOptions) error {
+	var (
+		t   terminalapi.Terminal
+		err error
+	)
+	if runtime.GOOS == "windows" {
+		t, err = tcell.New()
+	} else {
+		t, err = termbox.New(termbox.ColorMode(terminalapi.ColorMode256))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate terminal interface: %w", err)
+	}
+	defer t.Close()
+	return replay(t, termdash.Run, runID, storage, results, opts)
+}
+
+func replay(t terminalapi.Terminal, r runner, runID string, storage storage.Reader, results []export.Result, opts ReplayOptions) error {
+	if len(results) == 0 {
+		return fmt.Errorf("run %q has no results", runID)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := container.New(t, container.ID(rootID))
+	if err != nil {
+		return fmt.Errorf("failed to generate container: %w", err)
+	}
+	tl := newTimeline(results[0].Timestamp, results[len(results)-1].Timestamp, opts.Speed)
+	w, err := newWidgets(makeReplayParamsText(runID, tl))
+	if err != nil {
+		return fmt.Errorf("failed to generate widgets: %w", err)
+	}
+	w.navi.Write(replayNaviText, text.WriteReplace())
+	gridOpts, err := gridLayout(w)
+	if err != nil {
+		return fmt.Errorf("failed to build grid layout: %w", err)
+	}
+	if err := c.Update(rootID, gridOpts.base...); err != nil {
+		return fmt.Errorf("failed to update container: %w", err)
+	}
+	if opts.QueryRange == 0 {
+		opts.QueryRange = DefaultQueryRange
+	}
+	if opts.RedrawInternal == 0 {
+		opts.RedrawInternal = DefaultRedrawInterval
+	}
+	if opts.RedrawInternal < minRedrawInterval {
+		return fmt.Errorf("redrawInterval must be greater than %s", minRedrawInterval)
+	}
+
+	d := &drawer{
+		targetURL:      runID,
+		queryRange:     opts.QueryRange,
+		redrawInterval: opts.RedrawInternal,
+		widgets:        w,
+		gridOpts:       gridOpts,
+		metricsCh:      make(chan *attacker.Metrics),
+		chartDrawing:   atomic.NewBool(false),
+		metrics:        &attacker.Metrics{},
+		storage:        storage,
+		clock:          tl.now,
+	}
+	go d.updateMetrics(ctx)
+	go d.redrawMetrics(ctx)
+	go d.redrawCharts(ctx)
+	go d.playback(ctx, runID, tl, results)
+
+	k := replayKeybinds(cancel, c, d, tl)
+	return r(ctx, t, c, termdash.KeyboardSubscriber(k), termdash.RedrawInterval(opts.RedrawInternal))
+}
+
+// playback moves the timeline forward at the specified interval as redrawInterval,
+// and then updates the metrics and the progress to the ones as of the current position.
+func (d *drawer) playback(ctx context.Context, runID string, tl *timeline, results []export.Result) {
+	ticker := time.NewTicker(d.redrawInterval)
+	defer ticker.Stop()
+
+	// shown is the number of results the shown metrics are computed from.
+	shown := -1
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			tl.advance(now.Sub(last))
+			last = now
+			position := tl.now()
+			n := sort.Search(len(results), func(i int) bool {
+				return results[i].Timestamp.After(position)
+			})
+			if n != shown {
+				shown = n
+				select {
+				case d.metricsCh <- attacker.ResultsMetrics(results[:n]):
+				case <-ctx.Done():
+					return
+				}
+			}
+			d.widgets.progressGauge.Percent(tl.percent(), gauge.TextLabel(tl.String()))
+			d.widgets.paramsText.Write(makeReplayParamsText(runID, tl), text.WriteReplace())
+		}
+	}
+}
+
+func replayKeybinds(cancel context.CancelFunc, c *container.Container, dr *drawer, tl *timeline) func(*terminalapi.Keyboard) {
+	funcs := []func(){
+		func() { c.Update(chartID, dr.gridOpts.latency...) },
+		func() { c.Update(chartID, dr.gridOpts.percentiles...) },
+		func() { c.Update(chartID, dr.gridOpts.statusCodes...) },
+		func() { c.Update(chartID, dr.gridOpts.errors...) },
+		func() { c.Update(chartID, dr.gridOpts.rates...) },
+		func() { c.Update(chartID, dr.gridOpts.heatMap...) },
+	}
+	navigateFunc := navigateCharts(funcs)
+	return func(k *terminalapi.Keyboard) {
+		switch k.Key {
+		case keyboard.KeyCtrlC, 'q': // Quit
+			cancel()
+		case keyboard.KeySpace: // Play or pause
+			tl.togglePlaying()
+		case keyboard.KeyArrowLeft: // Seek backwards
+			tl.seek(-replaySeekStep)
+		case keyboard.KeyArrowRight: // Seek forwards
+			tl.seek(replaySeekStep)
+		case '+': // Speed up
+			tl.changeSpeed(2)
+		case '-': // Speed down
+			tl.changeSpeed(0.5)
+		case '0': // Rewind
+			tl.seek(-tl.length)
+		case 'H', 'h': // backwards
+			navigateFunc(true)
+		case 'L', 'l': // forwards
+			navigateFunc(false)
+		}
+	}
+}
+
+func makeReplayParamsText(runID string, tl *timeline) string {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return fmt.Sprintf(`Run: %s
+Began: %s
+Length: %v
+Speed: x%g
+`, runID, tl.start.Format(time.RFC3339), tl.length.Round(time.Millisecond), tl.speed)
+}
+
+// timeline is the virtual clock of a replay, which can be paused, scrubbed and sped up.
+type timeline struct {
+	mu       sync.Mutex
+	start    time.Time
+	length   time.Duration
+	position time.Duration
+	speed    float64
+	playing  bool
+}
+
+func newTimeline(start, end time.Time, speed float64) *timeline {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &timeline{
+		start:   start,
+		length:  end.Sub(start),
+		speed:   speed,
+		playing: true,
+	}
+}
+
+// now gives back the time at the current position.
+func (t *timeline) now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.start.Add(t.position)
+}
+
+// advance moves the position forward by the given real time multiplied by the speed, if playing.
+// It stops playing at the end.
+func (t *timeline) advance(elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.playing {
+		return
+	}
+	t.position += time.Duration(float64(elapsed) * t.speed)
+	if t.position >= t.length {
+		t.position = t.length
+		t.playing = false
+	}
+}
+
+// seek moves the position by the given offset, within the timeline.
+func (t *timeline) seek(offset time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.position += offset
+	if t.position < 0 {
+		t.position = 0
+	}
+	if t.position > t.length {
+		t.position = t.length
+	}
+}
+
+// togglePlaying pauses the playback, or resumes it. It starts over if at the end.
+func (t *timeline) togglePlaying() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.playing && t.position >= t.length {
+		t.position = 0
+	}
+	t.playing = !t.playing
+}
+
+// changeSpeed multiplies the speed by the given factor, within the range of the supported speeds.
+func (t *timeline) changeSpeed(factor float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.speed *= factor
+	if t.speed < minReplaySpeed {
+		t.speed = minReplaySpeed
+	}
+	if t.speed > maxReplaySpeed {
+		t.speed = maxReplaySpeed
+	}
+}
+
+func (t *timeline) percent() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.length <= 0 {
+		return 100
+	}
+	return int(float64(t.position) / float64(t.length) * 100)
+}
+
+func (t *timeline) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := "playing"
+	if !t.playing {
+		state = "paused"
+	}
+	return fmt.Sprintf("%s %v / %v (x%g)", state, t.position.Round(time.Second), t.length.Round(time.Second), t.speed)
+}
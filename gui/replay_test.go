@@ -0,0 +1,93 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/terminal/termbox"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/storage"
+)
+
+func TestReplay(t *testing.T) {
+	began := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	tests := []struct {
+		name    string
+		results []export.Result
+		runner  runner
+		wantErr bool
+	}{
+		{
+			name:    "successful running",
+			results: []export.Result{{Timestamp: began}, {Timestamp: began.Add(time.Second)}},
+			runner: func(context.Context, terminalapi.Terminal, *container.Container, ...termdash.Option) error {
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name:    "failed running",
+			results: []export.Result{{Timestamp: began}},
+			runner: func(context.Context, terminalapi.Terminal, *container.Container, ...termdash.Option) error {
+				return fmt.Errorf("error")
+			},
+			wantErr: true,
+		},
+		{
+			name: "no results",
+			runner: func(context.Context, terminalapi.Terminal, *container.Container, ...termdash.Option) error {
+				return nil
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := replay(&termbox.Terminal{}, tt.runner, "id", &storage.FakeStorage{}, tt.results, ReplayOptions{})
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	began := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	tl := newTimeline(began, began.Add(time.Minute), 0)
+	assert.Equal(t, 1.0, tl.speed, "speed defaults to 1")
+	assert.Equal(t, began, tl.now())
+
+	tl.advance(10 * time.Second)
+	assert.Equal(t, began.Add(10*time.Second), tl.now())
+	assert.Equal(t, 16, tl.percent())
+
+	tl.changeSpeed(2)
+	tl.advance(10 * time.Second)
+	assert.Equal(t, began.Add(30*time.Second), tl.now())
+	assert.Equal(t, "playing 30s / 1m0s (x2)", tl.String())
+
+	tl.togglePlaying()
+	tl.advance(10 * time.Second)
+	assert.Equal(t, began.Add(30*time.Second), tl.now(), "shouldn't move while paused")
+
+	tl.seek(-time.Hour)
+	assert.Equal(t, began, tl.now())
+	tl.seek(time.Hour)
+	assert.Equal(t, began.Add(time.Minute), tl.now())
+
+	tl.togglePlaying()
+	assert.Equal(t, began, tl.now(), "should start over at the end")
+	tl.advance(time.Hour)
+	assert.Equal(t, began.Add(time.Minute), tl.now())
+	assert.False(t, tl.playing, "should stop at the end")
+
+	tl.changeSpeed(1000)
+	assert.Equal(t, float64(maxReplaySpeed), tl.speed)
+	tl.changeSpeed(0.0001)
+	assert.Equal(t, minReplaySpeed, tl.speed)
+}
@@ -2,21 +2,50 @@ package gui
 
 import (
 	"fmt"
-	"time"
+	"strconv"
 
 	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/barchart"
 	"github.com/mum4k/termdash/widgets/gauge"
 	"github.com/mum4k/termdash/widgets/linechart"
 	"github.com/mum4k/termdash/widgets/text"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/storage"
+)
+
+const (
+	naviText         = "q: quit, Enter: attack, s: stop, p: pause/resume, +/-: rate up/down, r: input rate, l: next chart, h: prev chart"
+	ratePromptFormat = "New rate: %s_ (Enter: apply, Esc: cancel)"
 )
 
+// statusClassColors are the colors of the status classes on the charts.
+var statusClassColors = map[string]cell.Color{
+	"1xx":                   cell.ColorBlue,
+	"2xx":                   cell.ColorGreen,
+	"3xx":                   cell.ColorNumber(87),
+	"4xx":                   cell.ColorYellow,
+	"5xx":                   cell.ColorRed,
+	storage.ClassNoResponse: cell.ColorMagenta,
+}
+
 type LineChart interface {
 	widgetapi.Widget
 	Series(label string, values []float64, opts ...linechart.SeriesOption) error
 }
 
+type BarChart interface {
+	widgetapi.Widget
+	Values(values []int, max int, opts ...barchart.Option) error
+}
+
+type HeatMap interface {
+	widgetapi.Widget
+	Values(xLabels, yLabels []string, values [][]float64) error
+}
+
 type Text interface {
 	widgetapi.Widget
 	Write(text string, wOpts ...text.WriteOption) error
@@ -30,6 +59,8 @@ type Gauge interface {
 type chartLegend struct {
 	text     Text
 	cellOpts []cell.Option
+	// baselineCellOpts are the options for the series of the baseline, in a dimmer color.
+	baselineCellOpts []cell.Option
 }
 
 type widgets struct {
@@ -41,6 +72,8 @@ type widgets struct {
 	statusCodesText Text
 	errorsText      Text
 	othersText      Text
+	targetsText     Text
+	checksText      Text
 
 	percentilesChart LineChart
 	p99Legend        chartLegend
@@ -48,12 +81,33 @@ type widgets struct {
 	p90Legend        chartLegend
 	p50Legend        chartLegend
 
+	// statusChart plots the responses per second stacked by the status class.
+	statusChart LineChart
+	// statusLegends are in the order of storage.StatusClasses.
+	statusLegends []chartLegend
+
+	// errorCountChart and errorRatioChart plot the failed requests per second and their percentage of all requests.
+	errorCountChart LineChart
+	errorRatioChart LineChart
+
+	// rateChart plots the target rate, the achieved rate and the throughput per second.
+	rateChart        LineChart
+	targetRateLegend chartLegend
+	rateLegend       chartLegend
+	throughputLegend chartLegend
+
+	// histogramChart shows the number of responses in each latency bucket.
+	histogramChart BarChart
+
+	// latencyHeatMap shows the number of requests in each latency bucket every second.
+	latencyHeatMap HeatMap
+
 	progressGauge Gauge
 	navi          Text
 }
 
 // Thg given params is used for displayed text.
-func newWidgets(targetURL string, rate int, duration time.Duration, method string) (*widgets, error) {
+func newWidgets(params string) (*widgets, error) {
 	latencyChart, err := newLineChart()
 	if err != nil {
 		return nil, err
@@ -79,6 +133,14 @@ func newWidgets(targetURL string, rate int, duration time.Duration, method strin
 	if err != nil {
 		return nil, err
 	}
+	targetsText, err := newText("")
+	if err != nil {
+		return nil, err
+	}
+	checksText, err := newText("")
+	if err != nil {
+		return nil, err
+	}
 
 	p99Color := cell.FgColor(cell.ColorNumber(87))
 	p99Text, err := newText("p99", text.WriteCellOpts(p99Color))
@@ -105,12 +167,60 @@ func newWidgets(targetURL string, rate int, duration time.Duration, method strin
 		return nil, err
 	}
 
-	paramsText, err := newText(makeParamsText(targetURL, rate, duration, method))
+	statusChart, err := newLineChart()
 	if err != nil {
 		return nil, err
 	}
+	statusLegends := make([]chartLegend, 0, len(storage.StatusClasses))
+	for _, class := range storage.StatusClasses {
+		color := cell.FgColor(statusClassColors[class])
+		t, err := newText(class, text.WriteCellOpts(color))
+		if err != nil {
+			return nil, err
+		}
+		statusLegends = append(statusLegends, chartLegend{text: t, cellOpts: []cell.Option{color}})
+	}
 
-	navi, err := newText("q: quit, Enter: attack, l: next chart, h: prev chart")
+	errorCountChart, err := newLineChart()
+	if err != nil {
+		return nil, err
+	}
+	errorRatioChart, err := newLineChart()
+	if err != nil {
+		return nil, err
+	}
+
+	rateChart, err := newLineChart()
+	if err != nil {
+		return nil, err
+	}
+	targetRateColor := cell.FgColor(cell.ColorYellow)
+	targetRateText, err := newText("target", text.WriteCellOpts(targetRateColor))
+	if err != nil {
+		return nil, err
+	}
+	rateColor := cell.FgColor(cell.ColorGreen)
+	rateText, err := newText("rate", text.WriteCellOpts(rateColor))
+	if err != nil {
+		return nil, err
+	}
+	throughputColor := cell.FgColor(cell.ColorNumber(87))
+	throughputText, err := newText("throughput", text.WriteCellOpts(throughputColor))
+	if err != nil {
+		return nil, err
+	}
+
+	histogramChart, err := newBarChart()
+	if err != nil {
+		return nil, err
+	}
+
+	paramsText, err := newText(params)
+	if err != nil {
+		return nil, err
+	}
+
+	navi, err := newText(naviText)
 	if err != nil {
 		return nil, err
 	}
@@ -126,12 +236,24 @@ func newWidgets(targetURL string, rate int, duration time.Duration, method strin
 		statusCodesText:  statusCodesText,
 		errorsText:       errorsText,
 		othersText:       othersText,
+		targetsText:      targetsText,
+		checksText:       checksText,
 		progressGauge:    progressGauge,
 		percentilesChart: percentilesChart,
-		p99Legend:        chartLegend{p99Text, []cell.Option{p99Color}},
-		p95Legend:        chartLegend{p95Text, []cell.Option{p95Color}},
-		p90Legend:        chartLegend{p90Text, []cell.Option{p90Color}},
-		p50Legend:        chartLegend{p50Text, []cell.Option{p50Color}},
+		p99Legend:        chartLegend{p99Text, []cell.Option{p99Color}, []cell.Option{cell.FgColor(cell.ColorNumber(30))}},
+		p95Legend:        chartLegend{p95Text, []cell.Option{p95Color}, []cell.Option{cell.FgColor(cell.ColorNumber(22))}},
+		p90Legend:        chartLegend{p90Text, []cell.Option{p90Color}, []cell.Option{cell.FgColor(cell.ColorNumber(100))}},
+		p50Legend:        chartLegend{p50Text, []cell.Option{p50Color}, []cell.Option{cell.FgColor(cell.ColorNumber(90))}},
+		statusChart:      statusChart,
+		statusLegends:    statusLegends,
+		errorCountChart:  errorCountChart,
+		errorRatioChart:  errorRatioChart,
+		rateChart:        rateChart,
+		targetRateLegend: chartLegend{text: targetRateText, cellOpts: []cell.Option{targetRateColor}},
+		rateLegend:       chartLegend{text: rateText, cellOpts: []cell.Option{rateColor}},
+		throughputLegend: chartLegend{text: throughputText, cellOpts: []cell.Option{throughputColor}},
+		histogramChart:   histogramChart,
+		latencyHeatMap:   newHeatMap(cell.FgColor(cell.ColorGreen)),
 		navi:             navi,
 	}, nil
 }
@@ -144,6 +266,10 @@ func newLineChart() (LineChart, error) {
 	)
 }
 
+func newBarChart() (BarChart, error) {
+	return barchart.New(barchart.ShowValues())
+}
+
 func newText(s string, opts ...text.WriteOption) (Text, error) {
 	t, err := text.New(text.RollContent(), text.WrapAtWords())
 	if err != nil {
@@ -163,10 +289,18 @@ func newGauge() (Gauge, error) {
 	)
 }
 
-func makeParamsText(targetURL string, rate int, duration time.Duration, method string) string {
+func makeParamsText(targetURL string, a attacker.Attacker) string {
+	rate := strconv.Itoa(a.Rate())
+	if p := a.LoadProfile(); p.Type != "" && p.Type != attacker.LoadProfileConstant {
+		rate = p.String()
+	}
+	if stages := a.Stages(); len(stages) > 0 {
+		rate = fmt.Sprintf("%d stages", len(stages))
+	}
 	return fmt.Sprintf(`Target: %s
-Rate: %d
+Rate: %s
 Duration: %v
 Method: %s
-`, targetURL, rate, duration, method)
+Target rate: %.2f
+`, targetURL, rate, a.Duration(), a.Method(), a.TargetRate())
 }
@@ -11,6 +11,7 @@ import (
 	canvas "github.com/mum4k/termdash/private/canvas"
 	terminalapi "github.com/mum4k/termdash/terminal/terminalapi"
 	widgetapi "github.com/mum4k/termdash/widgetapi"
+	barchart "github.com/mum4k/termdash/widgets/barchart"
 	gauge "github.com/mum4k/termdash/widgets/gauge"
 	linechart "github.com/mum4k/termdash/widgets/linechart"
 	text "github.com/mum4k/termdash/widgets/text"
@@ -114,6 +115,197 @@ func (mr *MockLineChartMockRecorder) Series(label, values interface{}, opts ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Series", reflect.TypeOf((*MockLineChart)(nil).Series), varargs...)
 }
 
+// MockBarChart is a mock of BarChart interface.
+type MockBarChart struct {
+	ctrl     *gomock.Controller
+	recorder *MockBarChartMockRecorder
+}
+
+// MockBarChartMockRecorder is the mock recorder for MockBarChart.
+type MockBarChartMockRecorder struct {
+	mock *MockBarChart
+}
+
+// NewMockBarChart creates a new mock instance.
+func NewMockBarChart(ctrl *gomock.Controller) *MockBarChart {
+	mock := &MockBarChart{ctrl: ctrl}
+	mock.recorder = &MockBarChartMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBarChart) EXPECT() *MockBarChartMockRecorder {
+	return m.recorder
+}
+
+// Draw mocks base method.
+func (m *MockBarChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Draw", cvs, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Draw indicates an expected call of Draw.
+func (mr *MockBarChartMockRecorder) Draw(cvs, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Draw", reflect.TypeOf((*MockBarChart)(nil).Draw), cvs, meta)
+}
+
+// Keyboard mocks base method.
+func (m *MockBarChart) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keyboard", k, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Keyboard indicates an expected call of Keyboard.
+func (mr *MockBarChartMockRecorder) Keyboard(k, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keyboard", reflect.TypeOf((*MockBarChart)(nil).Keyboard), k, meta)
+}
+
+// Mouse mocks base method.
+func (m_2 *MockBarChart) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Mouse", m, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mouse indicates an expected call of Mouse.
+func (mr *MockBarChartMockRecorder) Mouse(m, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mouse", reflect.TypeOf((*MockBarChart)(nil).Mouse), m, meta)
+}
+
+// Options mocks base method.
+func (m *MockBarChart) Options() widgetapi.Options {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Options")
+	ret0, _ := ret[0].(widgetapi.Options)
+	return ret0
+}
+
+// Options indicates an expected call of Options.
+func (mr *MockBarChartMockRecorder) Options() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Options", reflect.TypeOf((*MockBarChart)(nil).Options))
+}
+
+// Values mocks base method.
+func (m *MockBarChart) Values(values []int, max int, opts ...barchart.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{values, max}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Values", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Values indicates an expected call of Values.
+func (mr *MockBarChartMockRecorder) Values(values, max interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{values, max}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Values", reflect.TypeOf((*MockBarChart)(nil).Values), varargs...)
+}
+
+// MockHeatMap is a mock of HeatMap interface.
+type MockHeatMap struct {
+	ctrl     *gomock.Controller
+	recorder *MockHeatMapMockRecorder
+}
+
+// MockHeatMapMockRecorder is the mock recorder for MockHeatMap.
+type MockHeatMapMockRecorder struct {
+	mock *MockHeatMap
+}
+
+// NewMockHeatMap creates a new mock instance.
+func NewMockHeatMap(ctrl *gomock.Controller) *MockHeatMap {
+	mock := &MockHeatMap{ctrl: ctrl}
+	mock.recorder = &MockHeatMapMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHeatMap) EXPECT() *MockHeatMapMockRecorder {
+	return m.recorder
+}
+
+// Draw mocks base method.
+func (m *MockHeatMap) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Draw", cvs, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Draw indicates an expected call of Draw.
+func (mr *MockHeatMapMockRecorder) Draw(cvs, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Draw", reflect.TypeOf((*MockHeatMap)(nil).Draw), cvs, meta)
+}
+
+// Keyboard mocks base method.
+func (m *MockHeatMap) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keyboard", k, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Keyboard indicates an expected call of Keyboard.
+func (mr *MockHeatMapMockRecorder) Keyboard(k, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keyboard", reflect.TypeOf((*MockHeatMap)(nil).Keyboard), k, meta)
+}
+
+// Mouse mocks base method.
+func (m_2 *MockHeatMap) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Mouse", m, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mouse indicates an expected call of Mouse.
+func (mr *MockHeatMapMockRecorder) Mouse(m, meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mouse", reflect.TypeOf((*MockHeatMap)(nil).Mouse), m, meta)
+}
+
+// Options mocks base method.
+func (m *MockHeatMap) Options() widgetapi.Options {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Options")
+	ret0, _ := ret[0].(widgetapi.Options)
+	return ret0
+}
+
+// Options indicates an expected call of Options.
+func (mr *MockHeatMapMockRecorder) Options() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Options", reflect.TypeOf((*MockHeatMap)(nil).Options))
+}
+
+// Values mocks base method.
+func (m *MockHeatMap) Values(xLabels, yLabels []string, values [][]float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Values", xLabels, yLabels, values)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Values indicates an expected call of Values.
+func (mr *MockHeatMapMockRecorder) Values(xLabels, yLabels, values interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Values", reflect.TypeOf((*MockHeatMap)(nil).Values), xLabels, yLabels, values)
+}
+
 // MockText is a mock of Text interface.
 type MockText struct {
 	ctrl     *gomock.Controller
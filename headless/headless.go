@@ -0,0 +1,258 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/storage"
+)
+
+const (
+	DefaultProgressInterval = time.Second
+
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Options struct {
+	// Stdout is where the final report is written.
+	Stdout io.Writer
+	// Stderr is where the progress lines are streamed.
+	Stderr io.Writer
+	// Format is the format of the final report, either "text" or "json".
+	Format           string
+	ProgressInterval time.Duration
+	// AbortConditions are watched while attacking, to abort the attack once any of them is met.
+	AbortConditions []attacker.AbortCondition
+}
+
+// Run performs an attack without rendering the TUI.
+// It keeps writing the progress to stderr and writes the final report to stdout.
+// The attack gets interrupted once SIGINT or SIGTERM is received.
+// If it gets aborted by an abort condition, the *attacker.AbortError is given back after writing the report.
+// If it gets interrupted, attacker.ErrInterrupted is given back after writing the report in the same way.
+func Run(targetURL string, s storage.Reader, a attacker.Attacker, opts Options) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel(attacker.ErrInterrupted)
+		case <-ctx.Done():
+		}
+	}()
+	return run(ctx, targetURL, s, a, opts)
+}
+
+func run(ctx context.Context, targetURL string, s storage.Reader, a attacker.Attacker, opts Options) error {
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+	if opts.Format != FormatText && opts.Format != FormatJSON {
+		return fmt.Errorf("unknown report format %q", opts.Format)
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = DefaultProgressInterval
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go attacker.WatchAbortConditions(ctx, cancel, s, opts.AbortConditions, attacker.DefaultAbortCheckInterval)
+
+	metricsCh := make(chan *attacker.Metrics)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.Attack(ctx, metricsCh)
+	}()
+
+	ticker := time.NewTicker(opts.ProgressInterval)
+	defer ticker.Stop()
+
+	metrics := &attacker.Metrics{}
+	start := time.Now()
+	for {
+		select {
+		case m := <-metricsCh:
+			if m != nil {
+				metrics = m
+			}
+		case <-ticker.C:
+			writeProgress(opts.Stderr, time.Since(start), a.Duration(), metrics)
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+			if err := writeReport(opts.Stdout, opts.Format, targetURL, a, metrics); err != nil {
+				return err
+			}
+			if metrics.Aborted != nil {
+				return metrics.Aborted
+			}
+			if errors.Is(context.Cause(ctx), attacker.ErrInterrupted) {
+				return attacker.ErrInterrupted
+			}
+			return nil
+		}
+	}
+}
+
+func writeProgress(w io.Writer, elapsed, duration time.Duration, m *attacker.Metrics) {
+	progress := ""
+	if duration > 0 {
+		percent := float64(elapsed) / float64(duration) * 100
+		if percent > 100 {
+			percent = 100
+		}
+		progress = fmt.Sprintf(" (%.0f%%)", percent)
+	}
+	if len(m.Stages) > 0 {
+		progress += fmt.Sprintf(" stage=%s", m.Stages[len(m.Stages)-1].Name)
+	}
+	fmt.Fprintf(w, "[%v%s] requests=%d rate=%.2f success=%.2f%% p50=%v p99=%v\n",
+		elapsed.Truncate(time.Second),
+		progress,
+		m.Requests,
+		m.Rate,
+		m.Success*100,
+		m.Latencies.P50,
+		m.Latencies.P99,
+	)
+}
+
+const reportTextFormat = `Target: %s %s
+Rate: %d
+Duration: %v
+
+Latencies:
+  Total: %v
+  Mean: %v
+  P50: %v
+  P90: %v
+  P95: %v
+  P99: %v
+  Max: %v
+  Min: %v
+Bytes In:
+  Total: %v
+  Mean: %v
+Bytes Out:
+  Total: %v
+  Mean: %v
+Requests: %d
+Rate: %f
+Throughput: %f
+Success: %f
+Earliest: %v
+Latest: %v
+End: %v
+`
+
+func writeReport(w io.Writer, format, targetURL string, a attacker.Attacker, m *attacker.Metrics) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	}
+
+	_, err := fmt.Fprintf(w, reportTextFormat,
+		a.Method(), targetURL,
+		a.Rate(),
+		a.Duration(),
+		m.Latencies.Total,
+		m.Latencies.Mean,
+		m.Latencies.P50,
+		m.Latencies.P90,
+		m.Latencies.P95,
+		m.Latencies.P99,
+		m.Latencies.Max,
+		m.Latencies.Min,
+		m.BytesIn.Total,
+		m.BytesIn.Mean,
+		m.BytesOut.Total,
+		m.BytesOut.Mean,
+		m.Requests,
+		m.Rate,
+		m.Throughput,
+		m.Success,
+		m.Earliest.Format(time.RFC3339),
+		m.Latest.Format(time.RFC3339),
+		m.End.Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	// To guarantee that status codes are in order
+	// taking the slice of keys and sorting them.
+	keys := make([]string, 0, len(m.StatusCodes))
+	for k := range m.StatusCodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(w, "Status Codes:")
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %q: %d\n", k, m.StatusCodes[k])
+	}
+	if len(m.Errors) > 0 {
+		fmt.Fprintln(w, "Errors:")
+		for _, e := range m.Errors {
+			fmt.Fprintf(w, "  - %s\n", e)
+		}
+	}
+	if len(m.Targets) > 1 {
+		fmt.Fprintln(w, "Targets:")
+		for _, t := range m.Targets {
+			fmt.Fprintf(w, "  %s %s: requests=%d success=%.2f%% p50=%v p99=%v\n",
+				t.Method, t.URL, t.Requests, t.Success*100, t.Latencies.P50, t.Latencies.P99)
+		}
+	}
+	if len(m.Stages) > 0 {
+		fmt.Fprintln(w, "Stages:")
+		for _, s := range m.Stages {
+			fmt.Fprintf(w, "  %s (rate=%d, duration=%v): requests=%d success=%.2f%% p50=%v p99=%v\n",
+				s.Name, s.Rate, s.Duration, s.Requests, s.Success*100, s.Latencies.P50, s.Latencies.P99)
+		}
+	}
+	if len(m.Histogram) > 0 {
+		fmt.Fprintln(w, "Histogram:")
+		for _, b := range m.Histogram {
+			upper := "+Inf"
+			if b.Max > 0 {
+				upper = b.Max.String()
+			}
+			fmt.Fprintf(w, "  [%v, %s): %d\n", b.Min, upper, b.Count)
+		}
+	}
+	if m.Aborted != nil {
+		fmt.Fprintf(w, "Aborted: %s (actual: %s)\n", m.Aborted.Condition, m.Aborted.Actual)
+	}
+	if len(m.Checks) > 0 {
+		fmt.Fprintln(w, "Checks:")
+		for _, c := range m.Checks {
+			verdict := "PASS"
+			if !c.Passed {
+				verdict = "FAIL"
+			}
+			fmt.Fprintf(w, "  %s %s (actual: %s)\n", verdict, c.Assertion, c.Actual)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package headless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/storage"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+type fakeAttacker struct {
+	attacker.FakeAttacker
+	metrics []*attacker.Metrics
+	err     error
+	// untilCanceled makes it give back the metrics only once the context gets canceled.
+	untilCanceled bool
+}
+
+func (f *fakeAttacker) Attack(ctx context.Context, metricsCh chan *attacker.Metrics) error {
+	if f.untilCanceled {
+		<-ctx.Done()
+	}
+	for _, m := range f.metrics {
+		metricsCh <- m
+	}
+	return f.err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		attacker   *fakeAttacker
+		format     string
+		wantErr    bool
+		wantStdout string
+	}{
+		{
+			name: "text report",
+			attacker: &fakeAttacker{
+				metrics: []*attacker.Metrics{
+					{Requests: 1},
+					{Requests: 2, StatusCodes: map[string]int{"200": 2}},
+				},
+			},
+			format:     FormatText,
+			wantStdout: "Requests: 2\n",
+		},
+		{
+			name: "text report with checks",
+			attacker: &fakeAttacker{
+				metrics: []*attacker.Metrics{
+					{Requests: 2, Checks: []attacker.CheckResult{{Assertion: "requests>=3", Actual: "2", Passed: false}}},
+				},
+			},
+			format:     FormatText,
+			wantStdout: "Checks:\n  FAIL requests>=3 (actual: 2)\n",
+		},
+		{
+			name: "text report with histogram",
+			attacker: &fakeAttacker{
+				metrics: []*attacker.Metrics{
+					{Requests: 2, Histogram: []attacker.HistogramBucket{
+						{Min: 0, Max: 10 * time.Millisecond, Count: 1},
+						{Min: 10 * time.Millisecond, Count: 1},
+					}},
+				},
+			},
+			format:     FormatText,
+			wantStdout: "Histogram:\n  [0s, 10ms): 1\n  [10ms, +Inf): 1\n",
+		},
+		{
+			name: "aborted attack",
+			attacker: &fakeAttacker{
+				metrics: []*attacker.Metrics{
+					{Requests: 2, Aborted: &attacker.AbortError{Condition: "error_ratio>0.05", Actual: "1"}},
+				},
+			},
+			format:     FormatText,
+			wantErr:    true,
+			wantStdout: "Aborted: error_ratio>0.05 (actual: 1)\n",
+		},
+		{
+			name: "json report",
+			attacker: &fakeAttacker{
+				metrics: []*attacker.Metrics{
+					{Requests: 2, StatusCodes: map[string]int{"200": 2}},
+				},
+			},
+			format:     FormatJSON,
+			wantStdout: `"requests": 2`,
+		},
+		{
+			name: "attack failed",
+			attacker: &fakeAttacker{
+				err: fmt.Errorf("error"),
+			},
+			format:  FormatText,
+			wantErr: true,
+		},
+		{
+			name:     "unknown format",
+			attacker: &fakeAttacker{},
+			format:   "xml",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			err := run(context.Background(), "http://host.xz", &storage.FakeStorage{}, tt.attacker, Options{
+				Stdout: stdout,
+				Format: tt.format,
+			})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Contains(t, stdout.String(), tt.wantStdout)
+		})
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	a := &fakeAttacker{
+		metrics: []*attacker.Metrics{
+			{Requests: 2, Stopped: true, StatusCodes: map[string]int{"200": 2}},
+		},
+		untilCanceled: true,
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(attacker.ErrInterrupted)
+	err := run(ctx, "http://host.xz", &storage.FakeStorage{}, a, Options{
+		Stdout: stdout,
+		Format: FormatText,
+	})
+	assert.ErrorIs(t, err, attacker.ErrInterrupted)
+	// The report is written with the final metrics anyway.
+	assert.Contains(t, stdout.String(), "Requests: 2\n")
+}
+
+func TestRunJSONReportIsDecodable(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	a := &fakeAttacker{
+		metrics: []*attacker.Metrics{
+			{Requests: 3, Success: 1, StatusCodes: map[string]int{"200": 3}},
+		},
+	}
+	require.NoError(t, run(context.Background(), "http://host.xz", &storage.FakeStorage{}, a, Options{
+		Stdout: stdout,
+		Format: FormatJSON,
+	}))
+
+	var got attacker.Metrics
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &got))
+	assert.Equal(t, uint64(3), got.Requests)
+	assert.Equal(t, map[string]int{"200": 3}, got.StatusCodes)
+}
+
+func TestWriteProgress(t *testing.T) {
+	b := &bytes.Buffer{}
+	writeProgress(b, 5*time.Second, 10*time.Second, &attacker.Metrics{
+		Requests: 250,
+		Rate:     50,
+		Success:  1,
+	})
+	assert.Equal(t, "[5s (50%)] requests=250 rate=50.00 success=100.00% p50=0s p99=0s\n", b.String())
+}
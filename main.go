@@ -26,6 +26,8 @@ import (
 	"github.com/nakabonne/ali/attacker"
 	"github.com/nakabonne/ali/export"
 	"github.com/nakabonne/ali/gui"
+	"github.com/nakabonne/ali/headless"
+	"github.com/nakabonne/ali/metricsserver"
 )
 
 var (
@@ -36,8 +38,10 @@ var (
 	commit  = "?"
 	date    = "?"
 
-	runGUI      = gui.Run
-	newAttacker = attacker.NewAttacker
+	runGUI       = gui.Run
+	runHeadless  = headless.Run
+	runReplayGUI = gui.Replay
+	newAttacker  = attacker.NewAttacker
 )
 
 type cli struct {
@@ -62,13 +66,33 @@ type cli struct {
 	tlsCertFile        string
 	tlsKeyFile         string
 	caCert             string
+	targetsFile        string
+	targetsFormat      string
+	targetsSelection   string
+	loadProfile        string
+	scenarioFile       string
+	assertions         []string
+	abortConditions    []string
 
 	//options for gui
 	queryRange     time.Duration
 	redrawInterval time.Duration
 
+	// options for headless mode
+	noTUI        bool
+	reportFormat string
+
 	// options for export
-	exportTo string
+	exportTo      string
+	exportAppend  bool
+	exportFormat  string
+	exportSchema  int
+	exportHeaders []string
+	// options for streaming results to remote services
+	exportRemotes        []string
+	exportRemoteInterval time.Duration
+	baseline             string
+	metricsAddr          string
 
 	debug   bool
 	version bool
@@ -77,6 +101,16 @@ type cli struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "report":
+			os.Exit(runReport(os.Args[2:], os.Stdout, os.Stderr))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:], os.Stdout, os.Stderr))
+		case "rerun":
+			os.Exit(runRerun(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
 	c, err := parseFlags(os.Stdout, os.Stderr)
 	if err != nil {
 		os.Exit(0)
@@ -98,7 +132,6 @@ func parseFlags(stdout, stderr io.Writer) (*cli, error) {
 	flagSet.StringVarP(&c.bodyFile, "body-file", "B", "", "The path to file whose content will be set as the http request body.")
 	flagSet.Int64VarP(&c.maxBody, "max-body", "M", attacker.DefaultMaxBody, "Max bytes to capture from response bodies. Give -1 for no limit.")
 	flagSet.BoolVarP(&c.version, "version", "v", false, "Print the current version.")
-	flagSet.BoolVar(&c.debug, "debug", false, "Run in debug mode.")
 	flagSet.BoolVarP(&c.noKeepAlive, "no-keepalive", "K", false, "Don't use HTTP persistent connection.")
 	flagSet.Uint64VarP(&c.workers, "workers", "w", attacker.DefaultWorkers, "Amount of initial workers to spawn.")
 	flagSet.Uint64VarP(&c.maxWorkers, "max-workers", "W", attacker.DefaultMaxWorkers, "Amount of maximum workers to spawn.")
@@ -109,12 +142,15 @@ func parseFlags(stdout, stderr io.Writer) (*cli, error) {
 	flagSet.StringVar(&c.caCert, "cacert", "", "PEM ca certificate file")
 	flagSet.StringVar(&c.tlsCertFile, "cert", "", "PEM encoded tls certificate file to use")
 	flagSet.StringVar(&c.tlsKeyFile, "key", "", "PEM encoded tls private key file to use")
-	// TODO: Re-enable when making it capable of drawing histogram bar chart.
-	//flagSet.StringVar(&c.buckets, "buckets", "", "Histogram buckets; comma-separated list.")
+	flagSet.StringVar(&c.buckets, "buckets", "", `Latency histogram buckets in ascending order; comma-separated list like "10ms,50ms,100ms". A [0, first) bucket is added if the first isn't 0.`)
+	flagSet.StringVar(&c.loadProfile, "load-profile", attacker.LoadProfileConstant, `How the rate changes over time; "constant" (uses --rate), "linear:from=10,to=500,over=5m", "step:from=10,step=50,every=30s[,to=500]" or "sine:mean=100,amp=50,period=10m".`)
+	flagSet.StringVar(&c.scenarioFile, "scenario", "", "The path to YAML or JSON file that lists the stages to be run back-to-back. Stages without targets use the target URL or \"--targets\".")
+	flagSet.StringArrayVar(&c.assertions, "assert", []string{}, `A condition the final metrics must satisfy, like "p99<250ms", "success>=0.999" or "rate>=95". Exits with 2 if any is failed. Can be used multiple times.`)
 	flagSet.StringVar(&c.resolvers, "resolvers", "", "Custom DNS resolver addresses; comma-separated list.")
-	flagSet.DurationVar(&c.queryRange, "query-range", gui.DefaultQueryRange, "The results within the given time range will be drawn on the charts")
-	flagSet.DurationVar(&c.redrawInterval, "redraw-interval", gui.DefaultRedrawInterval, "Specify how often it redraws the screen")
-	flagSet.StringVar(&c.exportTo, "export-to", "", "Export results to the given directory")
+	flagSet.StringVar(&c.targetsFile, "targets", "", "The path to file that lists the targets in the vegeta's target format, used instead of the target URL.")
+	flagSet.StringVar(&c.targetsFormat, "targets-format", attacker.TargetsFormatHTTP, `The format of the targets file; "http" or "json".`)
+	flagSet.StringVar(&c.targetsSelection, "targets-selection", attacker.SelectionRoundRobin, `How to pick a target from the targets file for each request; "round-robin" or "weighted". Weights can be given only in the "json" format.`)
+	c.addSessionFlags(flagSet)
 	flagSet.Usage = c.usage
 	if err := flagSet.Parse(os.Args[1:]); err != nil {
 		if !errors.Is(err, flag.ErrHelp) {
@@ -125,38 +161,150 @@ func parseFlags(stdout, stderr io.Writer) (*cli, error) {
 	return c, nil
 }
 
+// addSessionFlags adds the flags which don't change the attack itself, shared with the "rerun" subcommand.
+func (c *cli) addSessionFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.debug, "debug", false, "Run in debug mode.")
+	fs.StringArrayVar(&c.abortConditions, "abort-if", []string{}, `A condition to abort the attack early, evaluated on a rolling window, like "error_ratio>0.05,window=30s" or "p99>2s,window=10s,consecutive=3". Can be used multiple times.`)
+	fs.DurationVar(&c.queryRange, "query-range", gui.DefaultQueryRange, "The results within the given time range will be drawn on the charts")
+	fs.DurationVar(&c.redrawInterval, "redraw-interval", gui.DefaultRedrawInterval, "Specify how often it redraws the screen")
+	fs.StringVar(&c.exportTo, "export-to", "", "Export results to the given directory")
+	fs.BoolVar(&c.exportAppend, "export-append", false, `Append runs to the directory given with "--export-to" even if it exists, keeping the history of the runs in "index.json".`)
+	fs.StringVar(&c.exportFormat, "export-format", string(export.FormatCSV), `The format of the exported results; "csv", "jsonl" or "gob" (vegeta's binary format). The summary is always JSON.`)
+	fs.IntVar(&c.exportSchema, "export-schema", export.SchemaV1, `The version of the CSV schema; 1 for the original columns, or 2 to add "seq", "attack", "bytes_in", "bytes_out" and "error".`)
+	fs.StringArrayVar(&c.exportRemotes, "export-remote", []string{}, `Stream results to a remote service while attacking; "prometheus=<remote-write URL>", "influx=<write URL>" or "statsd=<host:port>". Can be used multiple times.`)
+	fs.DurationVar(&c.exportRemoteInterval, "export-remote-interval", export.DefaultRemoteFlushInterval, `How often results are sent to the services given with "--export-remote".`)
+	fs.StringArrayVar(&c.exportHeaders, "export-header", []string{}, `A response header to be exported, only with "--export-schema=2" or "--export-format=jsonl". Can be used multiple times.`)
+	fs.StringVar(&c.baseline, "baseline", "", "The path to the summary JSON of a prior run exported with \"--export-to\", to compare the run against.")
+	fs.StringVar(&c.metricsAddr, "metrics-addr", "", `Serve live metrics on "/metrics" at the given address like ":9100", for Prometheus to scrape.`)
+	fs.BoolVar(&c.noTUI, "no-tui", false, "Run the attack without the TUI, streaming progress to stderr and writing the final report to stdout.")
+	fs.StringVar(&c.reportFormat, "report-format", headless.FormatText, `The format of the final report printed in the "--no-tui" mode; "text" or "json".`)
+}
+
 func (c *cli) run(args []string) int {
 	if c.version {
 		fmt.Fprintf(c.stderr, "version=%s, commit=%s, buildDate=%s, os=%s, arch=%s\n", version, commit, date, runtime.GOOS, runtime.GOARCH)
 		return 0
 	}
-	if len(args) == 0 {
+	if len(args) == 0 && c.targetsFile == "" && c.scenarioFile == "" {
 		fmt.Fprintln(c.stderr, "no target given")
 		c.usage()
 		return 1
 	}
-	target := args[0]
-	if _, err := url.ParseRequestURI(target); err != nil {
-		fmt.Fprintf(c.stderr, "bad target URL: %v\n", err)
+	if len(args) > 0 && c.targetsFile != "" {
+		fmt.Fprintln(c.stderr, `only one of target URL and "--targets" can be specified`)
 		c.usage()
 		return 1
 	}
+	var target string
+	if len(args) > 0 {
+		target = args[0]
+		if _, err := url.ParseRequestURI(target); err != nil {
+			fmt.Fprintf(c.stderr, "bad target URL: %v\n", err)
+			c.usage()
+			return 1
+		}
+	}
 	opts, err := c.makeAttackerOptions()
 	if err != nil {
 		fmt.Fprintln(c.stderr, err.Error())
 		c.usage()
 		return 1
 	}
+	// The label of the targets to be displayed.
+	targetLabel := target
+	if len(opts.Targets) > 0 {
+		targetLabel = fmt.Sprintf("%s (%d targets)", c.targetsFile, len(opts.Targets))
+	}
+	if targetLabel == "" && len(opts.Stages) > 0 {
+		targetLabel = c.scenarioFile
+	}
+	opts.Config = attacker.NewConfig(target, opts)
+	if c.tlsCertFile != "" && c.tlsKeyFile != "" {
+		opts.Config.TLS.CertFile, _ = filepath.Abs(c.tlsCertFile)
+		opts.Config.TLS.KeyFile, _ = filepath.Abs(c.tlsKeyFile)
+	}
+	if c.caCert != "" {
+		opts.Config.TLS.CACertFile, _ = filepath.Abs(c.caCert)
+	}
+	return c.attack(target, targetLabel, opts)
+}
+
+// attack runs the attack with the given options, either on the TUI or headlessly.
+func (c *cli) attack(target, targetLabel string, opts *attacker.Options) int {
+	if c.noTUI && c.reportFormat != headless.FormatText && c.reportFormat != headless.FormatJSON {
+		fmt.Fprintf(c.stderr, "given report format %q isn't supported\n", c.reportFormat)
+		c.usage()
+		return 1
+	}
+
+	var abortConditions []attacker.AbortCondition
+	for _, raw := range c.abortConditions {
+		cond, err := attacker.ParseAbortCondition(raw)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "given abort condition %q has a wrong format: %v\n", raw, err)
+			c.usage()
+			return 1
+		}
+		abortConditions = append(abortConditions, cond)
+	}
+
+	var baseline *export.Baseline
+	if c.baseline != "" {
+		var err error
+		baseline, err = export.ReadBaseline(c.baseline)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err.Error())
+			return 1
+		}
+	}
+	opts.Baseline = baseline
+
+	var exporters export.MultiExporter
+	for _, raw := range c.exportRemotes {
+		cfg, err := export.ParseRemote(raw)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err.Error())
+			c.usage()
+			return 1
+		}
+		remote, err := export.NewRemoteExporter(cfg, c.exportRemoteInterval)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err.Error())
+			return 1
+		}
+		exporters = append(exporters, remote)
+	}
 
-	var exporter *export.FileExporter
 	if c.exportTo != "" {
+		format, err := export.ParseFormat(c.exportFormat)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err.Error())
+			c.usage()
+			return 1
+		}
+		if c.exportSchema != export.SchemaV1 && c.exportSchema != export.SchemaV2 {
+			fmt.Fprintf(c.stderr, "given export schema %d isn't supported\n", c.exportSchema)
+			c.usage()
+			return 1
+		}
+		if len(c.exportHeaders) > 0 && format != export.FormatJSONL && !(format == export.FormatCSV && c.exportSchema == export.SchemaV2) {
+			fmt.Fprintln(c.stderr, `"--export-header" requires "--export-schema=2" or "--export-format=jsonl"`)
+			c.usage()
+			return 1
+		}
 		if c.exportTo == "-" {
 			fmt.Fprintf(c.stderr, "export path %q is not supported\n", c.exportTo)
 			return 1
 		}
-		if _, err := os.Stat(c.exportTo); err == nil {
-			fmt.Fprintf(c.stderr, "export path %q already exists\n", c.exportTo)
-			return 1
+		if info, err := os.Stat(c.exportTo); err == nil {
+			if !c.exportAppend {
+				fmt.Fprintf(c.stderr, "export path %q already exists; give \"--export-append\" to append runs to it\n", c.exportTo)
+				return 1
+			}
+			if !info.IsDir() {
+				fmt.Fprintf(c.stderr, "export path %q isn't a directory\n", c.exportTo)
+				return 1
+			}
 		} else if !os.IsNotExist(err) {
 			fmt.Fprintf(c.stderr, "failed to stat export path %q: %v\n", c.exportTo, err)
 			return 1
@@ -165,9 +313,19 @@ func (c *cli) run(args []string) int {
 			fmt.Fprintf(c.stderr, "failed to create export directory %q: %v\n", c.exportTo, err)
 			return 1
 		}
-		exporter = export.NewFileExporter(c.exportTo)
+		exporters = append(exporters, export.NewFileExporterWithOptions(c.exportTo, export.FileOptions{
+			Format:        format,
+			SchemaVersion: c.exportSchema,
+			Headers:       c.exportHeaders,
+		}))
+	}
+	switch len(exporters) {
+	case 0:
+	case 1:
+		opts.Exporter = exporters[0]
+	default:
+		opts.Exporter = exporters
 	}
-	opts.Exporter = exporter
 
 	// Data points out of query range get flushed to prevent using heap more than need.
 	s, err := storage.NewStorage(c.queryRange * 2)
@@ -176,35 +334,95 @@ func (c *cli) run(args []string) int {
 		c.usage()
 		return 1
 	}
-	a, err := newAttacker(s, target, opts)
+	var w storage.Writer = s
+	var collector *metricsserver.Collector
+	if c.metricsAddr != "" {
+		collector = metricsserver.NewCollector(s)
+		w = collector
+	}
+	a, err := newAttacker(w, target, opts)
 	if err != nil {
 		fmt.Fprintf(c.stderr, "failed to initialize attacker: %v\n", err)
 		c.usage()
 		return 1
 	}
+	if collector != nil {
+		collector.SetAttacker(a)
+		server, err := metricsserver.Start(c.metricsAddr, collector)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err.Error())
+			return 1
+		}
+		defer server.Close()
+	}
 	setDebug(nil, c.debug)
 
-	if err := runGUI(target, s, a,
+	if c.noTUI {
+		if err := runHeadless(targetLabel, s, a,
+			headless.Options{
+				Stdout:          c.stdout,
+				Stderr:          c.stderr,
+				Format:          c.reportFormat,
+				AbortConditions: abortConditions,
+			},
+		); err != nil {
+			fmt.Fprintf(c.stderr, "failed to attack: %s\n", err.Error())
+			return 1
+		}
+		return c.checkResults(opts.Assertions, a.Checks())
+	}
+
+	if err := runGUI(targetLabel, s, a,
 		gui.Options{
-			QueryRange:     c.queryRange,
-			RedrawInternal: c.redrawInterval,
+			QueryRange:      c.queryRange,
+			RedrawInternal:  c.redrawInterval,
+			AbortConditions: abortConditions,
+			Baseline:        baseline,
 		},
 	); err != nil {
 		fmt.Fprintf(c.stderr, "failed to start application: %s\n", err.Error())
 		c.usage()
 		return 1
 	}
-	return 0
+	return c.checkResults(opts.Assertions, a.Checks())
+}
+
+// checkResults gives back the exit code, which is 2 if any of the given checks failed,
+// or if any of the given assertions wasn't evaluated because the attack didn't finish.
+func (c *cli) checkResults(assertions []attacker.Assertion, checks []attacker.CheckResult) int {
+	code := 0
+	failed := 0
+	for _, check := range checks {
+		if !check.Passed {
+			fmt.Fprintf(c.stderr, "check failed: %s (actual: %s)\n", check.Assertion, check.Actual)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(c.stderr, "%d of %d checks failed\n", failed, len(checks))
+		code = 2
+	}
+	if notEvaluated := len(assertions) - len(checks); notEvaluated > 0 {
+		fmt.Fprintf(c.stderr, "%d checks not evaluated\n", notEvaluated)
+		code = 2
+	}
+	return code
 }
 
 func (c *cli) usage() {
 	format := `Usage:
   ali [flags] <target URL>
+  ali [flags] --targets <targets file>
+  ali [flags] --scenario <scenario file> [<target URL>]
+  ali report [flags] <export directory>
+  ali replay [flags] <export directory>
+  ali rerun [flags] <config file>
 
 Flags:
 %s
 Examples:
   ali --duration=10m --rate=100 http://host.xz
+  ali --no-tui --report-format=json http://host.xz
 
 Author:
   Ryo Nakao <ryo@nakao.dev>
@@ -223,13 +441,9 @@ func (c *cli) makeAttackerOptions() (*attacker.Options, error) {
 
 	header := make(http.Header)
 	for _, hdr := range c.headers {
-		parts := strings.SplitN(hdr, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("given header %q has a wrong format", hdr)
-		}
-		key, val := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
-		if key == "" || val == "" {
-			return nil, fmt.Errorf("given header %q has a wrong format", hdr)
+		key, val, err := parseHeader(hdr)
+		if err != nil {
+			return nil, err
 		}
 		// NOTE: Add key/value directly to the http.Header (map[string][]string).
 		// http.Header.Add() canonicalizes keys but the vegeta API is used to test systems that require case-sensitive headers.
@@ -249,12 +463,38 @@ func (c *cli) makeAttackerOptions() (*attacker.Options, error) {
 		body = b
 	}
 
+	var targets []attacker.Target
+	if c.targetsFile != "" {
+		f, err := os.Open(c.targetsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %q: %w", c.targetsFile, err)
+		}
+		defer f.Close()
+		targets, err = attacker.ReadTargets(f, c.targetsFormat, body, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read targets from %q: %w", c.targetsFile, err)
+		}
+	}
+
+	var stages []attacker.Stage
+	if c.scenarioFile != "" {
+		f, err := os.Open(c.scenarioFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %q: %w", c.scenarioFile, err)
+		}
+		defer f.Close()
+		stages, err = attacker.ReadScenario(f, c.method, body, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scenario from %q: %w", c.scenarioFile, err)
+		}
+	}
+
 	localAddr := net.IPAddr{IP: net.ParseIP(c.localAddress)}
 
 	parsedBuckets, err := parseBucketOptions(c.buckets)
 
 	if err != nil {
-		return nil, fmt.Errorf("wrong buckets format %w", err)
+		return nil, fmt.Errorf("wrong buckets format: %w", err)
 	}
 
 	parsedResolvers, err := parseResolvers(c.resolvers)
@@ -262,6 +502,20 @@ func (c *cli) makeAttackerOptions() (*attacker.Options, error) {
 		return nil, err
 	}
 
+	loadProfile, err := parseLoadProfile(c.loadProfile)
+	if err != nil {
+		return nil, fmt.Errorf("wrong load profile format: %w", err)
+	}
+
+	var assertions []attacker.Assertion
+	for _, raw := range c.assertions {
+		assertion, err := attacker.ParseAssertion(raw)
+		if err != nil {
+			return nil, fmt.Errorf("given assertion %q has a wrong format: %w", raw, err)
+		}
+		assertions = append(assertions, assertion)
+	}
+
 	var certs []tls.Certificate
 	if c.tlsCertFile != "" && c.tlsKeyFile != "" {
 		cert, err := tls.LoadX509KeyPair(c.tlsCertFile, c.tlsKeyFile)
@@ -298,12 +552,30 @@ func (c *cli) makeAttackerOptions() (*attacker.Options, error) {
 		LocalAddr:          localAddr,
 		Buckets:            parsedBuckets,
 		Resolvers:          parsedResolvers,
+		Targets:            targets,
+		TargetSelection:    c.targetsSelection,
+		LoadProfile:        loadProfile,
+		Stages:             stages,
+		Assertions:         assertions,
 		InsecureSkipVerify: c.insecureSkipVerify,
 		TLSCertificates:    certs,
 		CACertificatePool:  caCertPool,
 	}, nil
 }
 
+// parseHeader parses the given header in the form of "<key>: <value>".
+func parseHeader(hdr string) (string, string, error) {
+	parts := strings.SplitN(hdr, ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("given header %q has a wrong format", hdr)
+	}
+	key, val := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if key == "" || val == "" {
+		return "", "", fmt.Errorf("given header %q has a wrong format", hdr)
+	}
+	return key, val, nil
+}
+
 func validateMethod(method string) bool {
 	switch method {
 	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
@@ -312,20 +584,31 @@ func validateMethod(method string) bool {
 	return false
 }
 
+// parseBucketOptions gives back the lower bounds of the buckets in ascending order.
+// The bucket from 0 is added if needed in the same way as vegeta, since the latencies lower than the first bucket are counted in the last one otherwise.
 func parseBucketOptions(rawBuckets string) ([]time.Duration, error) {
 	if rawBuckets == "" {
 		return []time.Duration{}, nil
 	}
 
 	stringBuckets := strings.Split(rawBuckets, ",")
-	result := make([]time.Duration, len(stringBuckets))
+	result := make([]time.Duration, 0, len(stringBuckets)+1)
 
-	for _, bucket := range stringBuckets {
+	for i, bucket := range stringBuckets {
 		trimmedBucket := strings.TrimSpace(bucket)
 		d, err := time.ParseDuration(trimmedBucket)
 		if err != nil {
 			return nil, err
 		}
+		if d < 0 {
+			return nil, fmt.Errorf("bucket %q must not be negative", trimmedBucket)
+		}
+		if i == 0 && d > 0 {
+			result = append(result, 0)
+		}
+		if len(result) > 0 && d <= result[len(result)-1] {
+			return nil, fmt.Errorf("buckets must be in ascending order: %q", rawBuckets)
+		}
 		result = append(result, d)
 	}
 
@@ -370,6 +653,80 @@ func parseResolvers(addrs string) ([]string, error) {
 	return result, nil
 }
 
+// parseLoadProfile parses the given load profile in the form of "<type>:<key>=<value>,...".
+func parseLoadProfile(raw string) (attacker.LoadProfile, error) {
+	if raw == "" || raw == attacker.LoadProfileConstant {
+		return attacker.LoadProfile{}, nil
+	}
+
+	var profile attacker.LoadProfile
+	typ, rawParams, _ := strings.Cut(raw, ":")
+	profile.Type = strings.TrimSpace(typ)
+	required := map[string][]string{
+		attacker.LoadProfileLinear: {"from", "to"},
+		attacker.LoadProfileStep:   {"from", "step", "every"},
+		attacker.LoadProfileSine:   {"mean", "amp", "period"},
+	}
+	optional := map[string][]string{
+		attacker.LoadProfileLinear: {"over"},
+		attacker.LoadProfileStep:   {"to"},
+	}
+	if _, ok := required[profile.Type]; !ok {
+		return attacker.LoadProfile{}, fmt.Errorf("unknown load profile %q", profile.Type)
+	}
+	allowed := make(map[string]bool)
+	for _, key := range append(required[profile.Type], optional[profile.Type]...) {
+		allowed[key] = true
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(rawParams, ",") {
+		if strings.TrimSpace(param) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			return attacker.LoadProfile{}, fmt.Errorf("given parameter %q has a wrong format", param)
+		}
+		key = strings.TrimSpace(key)
+		if !allowed[key] {
+			return attacker.LoadProfile{}, fmt.Errorf("unknown parameter %q for the %s profile", key, profile.Type)
+		}
+		params[key] = strings.TrimSpace(val)
+	}
+	for _, key := range required[profile.Type] {
+		if _, ok := params[key]; !ok {
+			return attacker.LoadProfile{}, fmt.Errorf("%q is required for the %s profile", key, profile.Type)
+		}
+	}
+
+	var err error
+	for key, val := range params {
+		switch key {
+		case "from":
+			profile.From, err = strconv.Atoi(val)
+		case "to":
+			profile.To, err = strconv.Atoi(val)
+		case "step":
+			profile.Step, err = strconv.Atoi(val)
+		case "mean":
+			profile.Mean, err = strconv.Atoi(val)
+		case "amp":
+			profile.Amplitude, err = strconv.Atoi(val)
+		case "over":
+			profile.Over, err = time.ParseDuration(val)
+		case "every":
+			profile.Every, err = time.ParseDuration(val)
+		case "period":
+			profile.Period, err = time.ParseDuration(val)
+		}
+		if err != nil {
+			return attacker.LoadProfile{}, fmt.Errorf("given parameter %q has a wrong value: %w", key, err)
+		}
+	}
+	return profile, nil
+}
+
 // Makes a new file under the ~/.config/ali only when debug use.
 func setDebug(w io.Writer, debug bool) {
 	if !debug {
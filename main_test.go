@@ -2,16 +2,23 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"log"
 	"math"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/gui"
+	"github.com/nakabonne/ali/headless"
+	"github.com/nakabonne/ali/storage"
 )
 
 func TestValidateMethod(t *testing.T) {
@@ -48,24 +55,35 @@ func TestParseFlags(t *testing.T) {
 		{
 			name: "with default options",
 			want: &cli{
-				rate:           50,
-				duration:       time.Second * 10,
-				timeout:        time.Second * 30,
-				method:         "GET",
-				headers:        []string{},
-				maxBody:        -1,
-				noKeepAlive:    false,
-				workers:        10,
-				maxWorkers:     math.MaxUint64,
-				connections:    10000,
-				stdout:         new(bytes.Buffer),
-				stderr:         new(bytes.Buffer),
-				noHTTP2:        false,
-				localAddress:   "0.0.0.0",
-				resolvers:      "",
-				queryRange:     30 * time.Second,
-				redrawInterval: 250 * time.Millisecond,
-				exportTo:       "",
+				rate:                 50,
+				duration:             time.Second * 10,
+				timeout:              time.Second * 30,
+				method:               "GET",
+				headers:              []string{},
+				maxBody:              -1,
+				noKeepAlive:          false,
+				workers:              10,
+				maxWorkers:           math.MaxUint64,
+				connections:          10000,
+				stdout:               new(bytes.Buffer),
+				stderr:               new(bytes.Buffer),
+				noHTTP2:              false,
+				localAddress:         "0.0.0.0",
+				resolvers:            "",
+				queryRange:           30 * time.Second,
+				redrawInterval:       250 * time.Millisecond,
+				reportFormat:         "text",
+				loadProfile:          "constant",
+				assertions:           []string{},
+				abortConditions:      []string{},
+				targetsFormat:        "http",
+				targetsSelection:     "round-robin",
+				exportTo:             "",
+				exportFormat:         "csv",
+				exportSchema:         1,
+				exportHeaders:        []string{},
+				exportRemotes:        []string{},
+				exportRemoteInterval: 5 * time.Second,
 			},
 			wantErr: false,
 		},
@@ -105,12 +123,48 @@ func TestRun(t *testing.T) {
 			args:     []string{"bad-url"},
 			wantCode: 1,
 		},
+		{
+			name:     "both target URL and targets file given",
+			cli:      &cli{targetsFile: "testdata/targets.txt"},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
 		{
 			name:     "failed to make options",
 			cli:      &cli{method: "WRONG"},
 			args:     []string{"bad-url"},
 			wantCode: 1,
 		},
+		{
+			name:     "unknown export format",
+			cli:      &cli{method: "GET", duration: time.Second, exportTo: filepath.Join(t.TempDir(), "results"), exportFormat: "xml"},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
+		{
+			name:     "bad metrics address",
+			cli:      &cli{method: "GET", duration: time.Second, metricsAddr: "host.xz:-1"},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
+		{
+			name:     "bad remote",
+			cli:      &cli{method: "GET", duration: time.Second, exportRemotes: []string{"graphite=localhost:2003"}},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
+		{
+			name:     "unknown export schema",
+			cli:      &cli{method: "GET", duration: time.Second, exportTo: filepath.Join(t.TempDir(), "results"), exportFormat: "csv", exportSchema: 3},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
+		{
+			name:     "export header with schema 1",
+			cli:      &cli{method: "GET", duration: time.Second, exportTo: filepath.Join(t.TempDir(), "results"), exportFormat: "csv", exportSchema: 1, exportHeaders: []string{"Content-Type"}},
+			args:     []string{"http://host.xz"},
+			wantCode: 1,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -330,6 +384,98 @@ func TestMakeAttackerOptions(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "targets file given",
+			cli: &cli{
+				method:           "GET",
+				targetsFile:      "testdata/targets.txt",
+				targetsFormat:    "http",
+				targetsSelection: "round-robin",
+			},
+			want: &attacker.Options{
+				Method:    "GET",
+				Body:      []byte{},
+				Header:    http.Header{},
+				HTTP2:     true,
+				KeepAlive: true,
+				Buckets:   []time.Duration{},
+				Targets: []attacker.Target{
+					{Method: "GET", URL: "http://host.xz/a", Body: []byte{}, Header: http.Header{"X-Foo": []string{"bar"}}, Weight: 1},
+					{Method: "POST", URL: "http://host.xz/b", Body: []byte(`{"foo": 1}`), Header: http.Header{}, Weight: 1},
+				},
+				TargetSelection: "round-robin",
+			},
+			wantErr: false,
+		},
+		{
+			name: "json targets file given",
+			cli: &cli{
+				method:           "GET",
+				targetsFile:      "testdata/targets.json",
+				targetsFormat:    "json",
+				targetsSelection: "weighted",
+			},
+			want: &attacker.Options{
+				Method:    "GET",
+				Body:      []byte{},
+				Header:    http.Header{},
+				HTTP2:     true,
+				KeepAlive: true,
+				Buckets:   []time.Duration{},
+				Targets: []attacker.Target{
+					{Method: "GET", URL: "http://host.xz/a", Body: []byte{}, Header: http.Header{}, Weight: 3},
+					{Method: "POST", URL: "http://host.xz/b", Body: []byte{}, Header: http.Header{"X-Foo": []string{"bar"}}, Weight: 1},
+				},
+				TargetSelection: "weighted",
+			},
+			wantErr: false,
+		},
+		{
+			name: "scenario file given",
+			cli: &cli{
+				method:       "GET",
+				scenarioFile: "testdata/scenario.yaml",
+			},
+			want: &attacker.Options{
+				Method:    "GET",
+				Body:      []byte{},
+				Header:    http.Header{},
+				HTTP2:     true,
+				KeepAlive: true,
+				Buckets:   []time.Duration{},
+				Stages: []attacker.Stage{
+					{Name: "warm-up", Rate: 20, Duration: 30 * time.Second, Header: http.Header{}},
+					{
+						Name:     "spike",
+						Rate:     1000,
+						Duration: 10 * time.Second,
+						Header:   http.Header{"X-Stage": []string{"spike"}},
+						Targets: []attacker.Target{
+							{Method: "POST", URL: "http://host.xz/a", Body: []byte(`{"foo": 1}`), Header: http.Header{}, Weight: 1},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "wrong scenario file given",
+			cli: &cli{
+				method:       "GET",
+				scenarioFile: "wrong",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "wrong targets file given",
+			cli: &cli{
+				method:      "GET",
+				targetsFile: "wrong",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "wrong format",
 			cli: &cli{
@@ -367,6 +513,130 @@ func TestMakeAttackerOptions(t *testing.T) {
 	}
 }
 
+func TestParseLoadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    attacker.LoadProfile
+		wantErr bool
+	}{
+		{
+			name: "constant",
+			raw:  "constant",
+			want: attacker.LoadProfile{},
+		},
+		{
+			name: "linear",
+			raw:  "linear:from=10,to=500,over=5m",
+			want: attacker.LoadProfile{Type: "linear", From: 10, To: 500, Over: 5 * time.Minute},
+		},
+		{
+			name: "step without upper limit",
+			raw:  "step:from=10, step=50, every=30s",
+			want: attacker.LoadProfile{Type: "step", From: 10, Step: 50, Every: 30 * time.Second},
+		},
+		{
+			name: "sine",
+			raw:  "sine:mean=100,amp=50,period=10m",
+			want: attacker.LoadProfile{Type: "sine", Mean: 100, Amplitude: 50, Period: 10 * time.Minute},
+		},
+		{
+			name:    "unknown profile",
+			raw:     "exponential:from=1",
+			wantErr: true,
+		},
+		{
+			name:    "required parameter missing",
+			raw:     "linear:from=10",
+			wantErr: true,
+		},
+		{
+			name:    "unknown parameter",
+			raw:     "linear:from=10,to=20,foo=1",
+			wantErr: true,
+		},
+		{
+			name:    "parameter of another profile",
+			raw:     "linear:from=10,to=20,period=1m",
+			wantErr: true,
+		},
+		{
+			name:    "step on sine",
+			raw:     "sine:mean=100,amp=50,period=10m,step=10",
+			wantErr: true,
+		},
+		{
+			name:    "wrong value",
+			raw:     "step:from=10,step=50,every=30",
+			wantErr: true,
+		},
+		{
+			name:    "wrong format",
+			raw:     "sine:mean",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLoadProfile(tt.raw)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseBucketOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			raw:  "",
+			want: []time.Duration{},
+		},
+		{
+			name: "bucket from 0 added",
+			raw:  "10ms, 50ms,100ms",
+			want: []time.Duration{0, 10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name: "starting with 0",
+			raw:  "0,1s",
+			want: []time.Duration{0, time.Second},
+		},
+		{
+			name:    "not ascending",
+			raw:     "50ms,10ms",
+			wantErr: true,
+		},
+		{
+			name:    "duplicated",
+			raw:     "10ms,10ms",
+			wantErr: true,
+		},
+		{
+			name:    "negative",
+			raw:     "-1ms,10ms",
+			wantErr: true,
+		},
+		{
+			name:    "wrong format",
+			raw:     "10ms,foo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBucketOptions(tt.raw)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestSetDebug(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -396,3 +666,298 @@ func TestSetDebug(t *testing.T) {
 		})
 	}
 }
+
+func TestRunHeadless(t *testing.T) {
+	origRunHeadless := runHeadless
+	origNewAttacker := newAttacker
+	defer func() {
+		runHeadless = origRunHeadless
+		newAttacker = origNewAttacker
+	}()
+	newAttacker = func(storage.Writer, string, *attacker.Options) (attacker.Attacker, error) {
+		return &attacker.FakeAttacker{}, nil
+	}
+
+	tests := []struct {
+		name         string
+		reportFormat string
+		headlessErr  error
+		wantCode     int
+	}{
+		{
+			name:         "successful attack",
+			reportFormat: "text",
+			wantCode:     0,
+		},
+		{
+			name:         "failed attack",
+			reportFormat: "json",
+			headlessErr:  errors.New("error"),
+			wantCode:     1,
+		},
+		{
+			name:         "unknown report format",
+			reportFormat: "xml",
+			wantCode:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOpts headless.Options
+			runHeadless = func(_ string, _ storage.Reader, _ attacker.Attacker, opts headless.Options) error {
+				gotOpts = opts
+				return tt.headlessErr
+			}
+			b := new(bytes.Buffer)
+			c := defaultCLI(b)
+			c.noTUI = true
+			c.reportFormat = tt.reportFormat
+			got := c.run([]string{"http://host.xz"})
+			assert.Equal(t, tt.wantCode, got)
+			if tt.wantCode == 0 {
+				assert.Equal(t, tt.reportFormat, gotOpts.Format)
+			}
+		})
+	}
+}
+
+func TestCheckResults(t *testing.T) {
+	p99, err := attacker.ParseAssertion("p99<250ms")
+	require.NoError(t, err)
+	success, err := attacker.ParseAssertion("success>=0.999")
+	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		assertions []attacker.Assertion
+		checks     []attacker.CheckResult
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:     "no checks",
+			wantCode: 0,
+		},
+		{
+			name:       "all passed",
+			assertions: []attacker.Assertion{p99},
+			checks: []attacker.CheckResult{
+				{Assertion: "p99<250ms", Actual: "120ms", Passed: true},
+			},
+			wantCode: 0,
+		},
+		{
+			name:       "some failed",
+			assertions: []attacker.Assertion{p99, success},
+			checks: []attacker.CheckResult{
+				{Assertion: "p99<250ms", Actual: "120ms", Passed: true},
+				{Assertion: "success>=0.999", Actual: "0.5", Passed: false},
+			},
+			wantCode:   2,
+			wantStderr: "1 of 2 checks failed\n",
+		},
+		{
+			name:       "not evaluated",
+			assertions: []attacker.Assertion{p99, success},
+			wantCode:   2,
+			wantStderr: "2 checks not evaluated\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			c := defaultCLI(b)
+			assert.Equal(t, tt.wantCode, c.checkResults(tt.assertions, tt.checks))
+			assert.Contains(t, b.String(), tt.wantStderr)
+		})
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStdout string
+	}{
+		{
+			name: "no directory",
+			args: []string{},
+			want: 1,
+		},
+		{
+			name:       "with id",
+			args:       []string{"--id", "00000000-0000-0000-0000-000000000000", filepath.Join("testdata", "export", "basic")},
+			want:       0,
+			wantStdout: "Run: 00000000-0000-0000-0000-000000000000\n",
+		},
+		{
+			name: "unknown id",
+			args: []string{"--id", "unknown", filepath.Join("testdata", "export", "basic")},
+			want: 1,
+		},
+		{
+			name: "html",
+			args: []string{"--html", filepath.Join(t.TempDir(), "report.html"), filepath.Join("testdata", "export", "basic")},
+			want: 0,
+		},
+		{
+			name: "html with unknown id",
+			args: []string{"--html", filepath.Join(t.TempDir(), "report.html"), "--id", "unknown", filepath.Join("testdata", "export", "basic")},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.want, runReport(tt.args, &stdout, &stderr))
+			assert.True(t, strings.HasPrefix(stdout.String(), tt.wantStdout))
+		})
+	}
+}
+
+func TestRunReplay(t *testing.T) {
+	origRunReplayGUI := runReplayGUI
+	defer func() { runReplayGUI = origRunReplayGUI }()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    int
+		wantID  string
+		wantLen int
+	}{
+		{
+			name: "no directory",
+			args: []string{},
+			want: 1,
+		},
+		{
+			name: "bad speed",
+			args: []string{"--speed", "0", filepath.Join("testdata", "export", "basic")},
+			want: 1,
+		},
+		{
+			name:    "last run",
+			args:    []string{filepath.Join("testdata", "export", "basic")},
+			want:    0,
+			wantID:  "00000000-0000-0000-0000-000000000000",
+			wantLen: 3,
+		},
+		{
+			name: "unknown id",
+			args: []string{"--id", "unknown", filepath.Join("testdata", "export", "basic")},
+			want: 1,
+		},
+		{
+			name: "run without results",
+			args: []string{filepath.Join("testdata", "export", "noresults")},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			var gotLen int
+			runReplayGUI = func(id string, _ storage.Reader, results []export.Result, _ gui.ReplayOptions) error {
+				gotID, gotLen = id, len(results)
+				return nil
+			}
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.want, runReplay(tt.args, &stdout, &stderr))
+			assert.Equal(t, tt.wantID, gotID)
+			assert.Equal(t, tt.wantLen, gotLen)
+		})
+	}
+}
+
+// passedAttacker is an attacker whose assertions have all been evaluated and passed.
+type passedAttacker struct {
+	attacker.FakeAttacker
+	checks []attacker.CheckResult
+}
+
+func newPassedAttacker(assertions []attacker.Assertion) *passedAttacker {
+	checks := make([]attacker.CheckResult, 0, len(assertions))
+	for _, a := range assertions {
+		checks = append(checks, attacker.CheckResult{Assertion: a.String(), Passed: true})
+	}
+	return &passedAttacker{checks: checks}
+}
+
+func (p *passedAttacker) Checks() []attacker.CheckResult {
+	return p.checks
+}
+
+func TestRunRerun(t *testing.T) {
+	origRunGUI := runGUI
+	origNewAttacker := newAttacker
+	defer func() {
+		runGUI = origRunGUI
+		newAttacker = origNewAttacker
+	}()
+	runGUI = func(string, storage.Reader, attacker.Attacker, gui.Options) error {
+		return nil
+	}
+
+	config := filepath.Join("testdata", "rerun", "config.json")
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantTarget string
+		wantHeader http.Header
+	}{
+		{
+			name: "no config",
+			args: []string{},
+			want: 1,
+		},
+		{
+			name: "missing config",
+			args: []string{filepath.Join("testdata", "rerun", "missing.json")},
+			want: 1,
+		},
+		{
+			name: "redacted header left",
+			args: []string{config},
+			want: 1,
+		},
+		{
+			name: "bad header",
+			args: []string{"--header", "Authorization", config},
+			want: 1,
+		},
+		{
+			name:       "redacted header given",
+			args:       []string{"--header", "Authorization: Bearer xxx", config},
+			want:       0,
+			wantTarget: "http://localhost:8080/",
+			wantHeader: http.Header{"Authorization": []string{"Bearer xxx"}, "X-Foo": []string{"bar"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				gotTarget string
+				gotOpts   *attacker.Options
+			)
+			newAttacker = func(_ storage.Writer, target string, opts *attacker.Options) (attacker.Attacker, error) {
+				gotTarget, gotOpts = target, opts
+				return newPassedAttacker(opts.Assertions), nil
+			}
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.want, runRerun(tt.args, &stdout, &stderr), stderr.String())
+			assert.Equal(t, tt.wantTarget, gotTarget)
+			if tt.want != 0 {
+				return
+			}
+			assert.Equal(t, tt.wantHeader, gotOpts.Header)
+			assert.Equal(t, 20, gotOpts.Rate)
+			assert.Equal(t, time.Second, gotOpts.Duration)
+			assert.Equal(t, http.MethodPost, gotOpts.Method)
+			assert.Equal(t, []byte(`{"foo":1}`), gotOpts.Body)
+			assert.Len(t, gotOpts.Assertions, 1)
+			assert.NotNil(t, gotOpts.Config)
+		})
+	}
+}
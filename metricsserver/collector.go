@@ -0,0 +1,116 @@
+// Package metricsserver serves the live metrics of attacks in the Prometheus text format,
+// so that Prometheus can scrape ali alongside the system under test.
+package metricsserver
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/storage"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histogram, same as the ones of the Prometheus client.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector collects the metrics from the results on their way into the storage.
+// The counters are cumulative across attacks, as Prometheus counters are expected to be.
+type Collector struct {
+	storage.Writer
+	buckets []float64
+
+	mu           sync.Mutex
+	requests     uint64
+	codes        map[uint16]uint64
+	bucketCounts []uint64
+	latencySum   float64
+	attacker     attacker.Attacker
+}
+
+// NewCollector gives back a collector which inserts the results into the given storage.
+func NewCollector(w storage.Writer) *Collector {
+	return &Collector{
+		Writer:       w,
+		buckets:      DefaultBuckets,
+		codes:        make(map[uint16]uint64),
+		bucketCounts: make([]uint64, len(DefaultBuckets)),
+	}
+}
+
+// SetAttacker makes the gauges of the given attacker served, which has to be the one inserting into the collector.
+func (c *Collector) SetAttacker(a attacker.Attacker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attacker = a
+}
+
+func (c *Collector) Insert(r *storage.Result) error {
+	seconds := r.Latency.Seconds()
+	c.mu.Lock()
+	c.requests++
+	c.codes[r.Code]++
+	c.latencySum += seconds
+	for i, le := range c.buckets {
+		if seconds <= le {
+			c.bucketCounts[i]++
+		}
+	}
+	c.mu.Unlock()
+	return c.Writer.Insert(r)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	c.write(b)
+	_ = b.Flush()
+}
+
+func (c *Collector) write(b *bufio.Writer) {
+	c.mu.Lock()
+	requests, latencySum, a := c.requests, c.latencySum, c.attacker
+	bucketCounts := append([]uint64(nil), c.bucketCounts...)
+	codeCounts := make(map[int]uint64, len(c.codes))
+	for code, n := range c.codes {
+		codeCounts[int(code)] = n
+	}
+	c.mu.Unlock()
+	codes := make([]int, 0, len(codeCounts))
+	for code := range codeCounts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	writeHeader(b, "ali_requests_total", "counter", "The number of completed requests.")
+	fmt.Fprintf(b, "ali_requests_total %d\n", requests)
+
+	writeHeader(b, "ali_responses_total", "counter", "The number of completed requests per status code, where 0 means no response was received.")
+	for _, code := range codes {
+		fmt.Fprintf(b, "ali_responses_total{code=\"%d\"} %d\n", code, codeCounts[code])
+	}
+
+	writeHeader(b, "ali_request_duration_seconds", "histogram", "The latencies of the completed requests.")
+	for i, le := range c.buckets {
+		fmt.Fprintf(b, "ali_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'f', -1, 64), bucketCounts[i])
+	}
+	fmt.Fprintf(b, "ali_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", requests)
+	fmt.Fprintf(b, "ali_request_duration_seconds_sum %s\n", strconv.FormatFloat(latencySum, 'g', -1, 64))
+	fmt.Fprintf(b, "ali_request_duration_seconds_count %d\n", requests)
+
+	if a == nil {
+		return
+	}
+	writeHeader(b, "ali_in_flight_requests", "gauge", "The number of requests issued but not completed yet, which is the number of busy workers.")
+	fmt.Fprintf(b, "ali_in_flight_requests %d\n", a.InFlight())
+	writeHeader(b, "ali_target_rate", "gauge", "The rate per second the ongoing attack is aiming at.")
+	fmt.Fprintf(b, "ali_target_rate %s\n", strconv.FormatFloat(a.TargetRate(), 'g', -1, 64))
+}
+
+func writeHeader(b *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
@@ -0,0 +1,98 @@
+package metricsserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/storage"
+)
+
+// recordingStorage records the inserted results.
+type recordingStorage struct {
+	results []*storage.Result
+}
+
+func (s *recordingStorage) Insert(r *storage.Result) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func TestCollector(t *testing.T) {
+	s := &recordingStorage{}
+	c := NewCollector(s)
+	for _, r := range []*storage.Result{
+		{Code: 200, Latency: 3 * time.Millisecond},
+		{Code: 200, Latency: 20 * time.Millisecond},
+		{Code: 0, Latency: 30 * time.Second},
+	} {
+		require.NoError(t, c.Insert(r))
+	}
+	assert.Len(t, s.results, 3, "results should be passed through")
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	want := `# HELP ali_requests_total The number of completed requests.
+# TYPE ali_requests_total counter
+ali_requests_total 3
+# HELP ali_responses_total The number of completed requests per status code, where 0 means no response was received.
+# TYPE ali_responses_total counter
+ali_responses_total{code="0"} 1
+ali_responses_total{code="200"} 2
+# HELP ali_request_duration_seconds The latencies of the completed requests.
+# TYPE ali_request_duration_seconds histogram
+ali_request_duration_seconds_bucket{le="0.005"} 1
+ali_request_duration_seconds_bucket{le="0.01"} 1
+ali_request_duration_seconds_bucket{le="0.025"} 2
+ali_request_duration_seconds_bucket{le="0.05"} 2
+ali_request_duration_seconds_bucket{le="0.1"} 2
+ali_request_duration_seconds_bucket{le="0.25"} 2
+ali_request_duration_seconds_bucket{le="0.5"} 2
+ali_request_duration_seconds_bucket{le="1"} 2
+ali_request_duration_seconds_bucket{le="2.5"} 2
+ali_request_duration_seconds_bucket{le="5"} 2
+ali_request_duration_seconds_bucket{le="10"} 2
+ali_request_duration_seconds_bucket{le="+Inf"} 3
+ali_request_duration_seconds_sum 30.023
+ali_request_duration_seconds_count 3
+`
+	assert.Equal(t, want, rec.Body.String())
+
+	c.SetAttacker(&attacker.FakeAttacker{})
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.True(t, strings.HasSuffix(rec.Body.String(), `# HELP ali_in_flight_requests The number of requests issued but not completed yet, which is the number of busy workers.
+# TYPE ali_in_flight_requests gauge
+ali_in_flight_requests 0
+# HELP ali_target_rate The rate per second the ongoing attack is aiming at.
+# TYPE ali_target_rate gauge
+ali_target_rate 0
+`))
+}
+
+func TestStart(t *testing.T) {
+	c := NewCollector(&recordingStorage{})
+	require.NoError(t, c.Insert(&storage.Result{Code: 200, Latency: time.Millisecond}))
+	server, err := Start("127.0.0.1:0", c)
+	require.NoError(t, err)
+	defer server.Close()
+
+	resp, err := http.Get("http://" + server.Addr + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "ali_requests_total 1\n")
+
+	_, err = Start("127.0.0.1:-1", c)
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package metricsserver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Start starts serving the metrics of the given collector on "/metrics" at the given address in the background.
+// It fails right away if the address can't be listened on.
+func Start(addr string, c *Collector) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %q: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c)
+	// Addr is set to the actual one, which is chosen by the system if the port is 0.
+	server := &http.Server{Addr: ln.Addr().String(), Handler: mux}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("failed to serve metrics: %v\n", err)
+		}
+	}()
+	return server, nil
+}
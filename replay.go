@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/nakabonne/ali/attacker"
+	"github.com/nakabonne/ali/export"
+	"github.com/nakabonne/ali/gui"
+	"github.com/nakabonne/ali/storage"
+)
+
+// runReplay runs the "replay" subcommand, which plays back a run exported by "--export-to" on the TUI.
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ali replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		id   string
+		opts gui.ReplayOptions
+	)
+	fs.StringVar(&id, "id", "", "The id of the run to be replayed. The last run in the directory is replayed if not given.")
+	fs.Float64Var(&opts.Speed, "speed", 1, "The initial playback speed, where 1 means the original pace.")
+	fs.DurationVar(&opts.QueryRange, "query-range", gui.DefaultQueryRange, "The results within the given time range will be drawn on the charts")
+	fs.DurationVar(&opts.RedrawInternal, "redraw-interval", gui.DefaultRedrawInterval, "Specify how often it redraws the screen")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, `Usage:
+  ali replay [flags] <export directory>
+
+Flags:
+%s
+Examples:
+  ali replay ./results/
+  ali replay --id 00000000-0000-0000-0000-000000000000 --speed 4 ./results/
+`, fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "an export directory is required")
+		fs.Usage()
+		return 1
+	}
+	if opts.Speed <= 0 {
+		fmt.Fprintln(stderr, "speed must be greater than 0")
+		fs.Usage()
+		return 1
+	}
+
+	run, err := readReplayRun(fs.Arg(0), id)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	results := run.Results
+	if len(results) == 0 {
+		fmt.Fprintf(stderr, "run %q has no results\n", run.ID)
+		return 1
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+
+	// Keep all data points in a single partition, so that the whole run can be scrubbed.
+	length := results[len(results)-1].Timestamp.Sub(results[0].Timestamp)
+	s, err := storage.NewStorage(length + opts.QueryRange*2)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to initialize time-series storage: %v\n", err)
+		return 1
+	}
+	if err := attacker.InsertResults(s, results); err != nil {
+		fmt.Fprintf(stderr, "failed to load results: %v\n", err)
+		return 1
+	}
+	if err := runReplayGUI(run.ID, s, results, opts); err != nil {
+		fmt.Fprintf(stderr, "failed to replay: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// readReplayRun reads the run with the given id, or the last run if the id is empty.
+func readReplayRun(dir, id string) (*export.RunRecord, error) {
+	reader := export.NewFileReader(dir)
+	if id != "" {
+		return reader.Run(id)
+	}
+	runs, err := reader.Runs()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no runs found in %q", dir)
+	}
+	return runs[len(runs)-1], nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/nakabonne/ali/report"
+)
+
+// runReport runs the "report" subcommand, which renders the results exported by "--export-to".
+func runReport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ali report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var id, htmlPath string
+	fs.StringVar(&id, "id", "", "The id of the only run to be rendered. All runs in the directory are rendered if not given.")
+	fs.StringVar(&htmlPath, "html", "", "Write a self-contained HTML report with charts to the given file, instead of printing tables.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, `Usage:
+  ali report [flags] <export directory>
+
+Flags:
+%s
+Examples:
+  ali report ./results/
+  ali report --id 00000000-0000-0000-0000-000000000000 ./results/
+  ali report --html report.html ./results/
+`, fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "an export directory is required")
+		fs.Usage()
+		return 1
+	}
+	opts := report.Options{ID: id}
+	if htmlPath != "" {
+		if err := writeHTMLReport(htmlPath, fs.Arg(0), opts); err != nil {
+			fmt.Fprintf(stderr, "failed to report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := report.Write(stdout, fs.Arg(0), opts); err != nil {
+		fmt.Fprintf(stderr, "failed to report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeHTMLReport(path, dir string, opts report.Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", path, err)
+	}
+	if err := report.WriteHTML(file, dir, opts); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %q: %w", path, err)
+	}
+	return nil
+}
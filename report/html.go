@@ -0,0 +1,341 @@
+package report
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nakabonne/ali/export"
+)
+
+const histogramBins = 20
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+var htmlReport = template.Must(template.New("report").Parse(htmlTemplate))
+
+// WriteHTML reads the runs in the given export directory, and then writes a self-contained HTML report into w.
+// Charts are inlined as SVG, and the summaries and the raw results are embedded as JSON,
+// so that it can be viewed without network access.
+func WriteHTML(w io.Writer, dir string, opts Options) error {
+	runs, err := readRuns(dir, opts.ID)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no runs found in %q", dir)
+	}
+	data := struct {
+		Runs []htmlRun
+	}{}
+	for _, run := range runs {
+		r, err := newHTMLRun(run)
+		if err != nil {
+			return err
+		}
+		data.Runs = append(data.Runs, r)
+	}
+	if err := htmlReport.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+type htmlRun struct {
+	ID               string
+	Parameters       []keyValue
+	Rows             []htmlRow
+	StatusCodes      []codeCount
+	Errors           []errorCount
+	LatencyChart     template.HTML
+	PercentilesChart template.HTML
+	Histogram        template.HTML
+	SummaryJSON      template.JS
+	ResultsJSON      template.JS
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+type htmlRow struct {
+	Target   string
+	Requests int
+	Success  string
+	Mean     string
+	P50      string
+	P90      string
+	P95      string
+	P99      string
+	Max      string
+}
+
+type codeCount struct {
+	Code  uint16
+	Count int
+}
+
+// errorCount is the number of failed requests of a target with the same status code.
+type errorCount struct {
+	Method string
+	URL    string
+	Code   uint16
+	Count  int
+}
+
+// jsonResult is a result embedded in the report, which has the same fields as the results file.
+type jsonResult struct {
+	Timestamp  time.Time `json:"timestamp"`
+	LatencyNS  *float64  `json:"latency_ns"`
+	URL        string    `json:"url"`
+	Method     string    `json:"method"`
+	StatusCode uint16    `json:"status_code"`
+}
+
+func newHTMLRun(run *export.RunRecord) (htmlRun, error) {
+	r := htmlRun{
+		ID:          run.ID,
+		Parameters:  parametersOf(run),
+		StatusCodes: statusCodes(run.Results),
+		Errors:      errorCounts(run.Results),
+	}
+	rows := targetStats(run.Results)
+	if len(rows) > 1 {
+		rows = append(rows, newStats("Total", run.Results))
+	}
+	for _, s := range rows {
+		r.Rows = append(r.Rows, htmlRow{
+			Target:   s.label,
+			Requests: s.requests,
+			Success:  fmt.Sprintf("%.2f%%", s.success*100),
+			Mean:     round(s.mean).String(),
+			P50:      round(s.p50).String(),
+			P90:      round(s.p90).String(),
+			P95:      round(s.p95).String(),
+			P99:      round(s.p99).String(),
+			Max:      round(s.max).String(),
+		})
+	}
+
+	earliest, _ := timeRange(run.Results)
+	r.LatencyChart = lineChart("Latency", "s", "ms", []series{latencySeries(run.Results, earliest)})
+	r.PercentilesChart = lineChart("Percentiles", "s", "ms", percentileSeries(run.Results, earliest))
+	r.Histogram = barChart("Latency histogram", "ms", histogram(run.Results))
+
+	summaryJSON, err := json.Marshal(run.Summary)
+	if err != nil {
+		return htmlRun{}, fmt.Errorf("failed to encode the summary of run %q: %w", run.ID, err)
+	}
+	results := make([]jsonResult, 0, len(run.Results))
+	for _, res := range run.Results {
+		jr := jsonResult{Timestamp: res.Timestamp, URL: res.URL, Method: res.Method, StatusCode: res.StatusCode}
+		// JSON can't represent NaN and Inf, which are encoded as null instead.
+		if finite(res.LatencyNS) {
+			latency := res.LatencyNS
+			jr.LatencyNS = &latency
+		}
+		results = append(results, jr)
+	}
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return htmlRun{}, fmt.Errorf("failed to encode the results of run %q: %w", run.ID, err)
+	}
+	r.SummaryJSON = template.JS(summaryJSON)
+	r.ResultsJSON = template.JS(resultsJSON)
+	return r, nil
+}
+
+func parametersOf(run *export.RunRecord) []keyValue {
+	var params []keyValue
+	if s := run.Summary; s != nil {
+		params = append(params,
+			keyValue{"Target", s.Target.Method + " " + s.Target.URL},
+			keyValue{"Rate", fmt.Sprintf("%d", s.Parameters.Rate)},
+			keyValue{"Duration", time.Duration(s.Parameters.DurationSeconds * float64(time.Second)).String()},
+		)
+		if s.Parameters.LoadProfile != "" {
+			params = append(params, keyValue{"Load profile", s.Parameters.LoadProfile})
+		}
+		params = append(params, keyValue{"Throughput", fmt.Sprintf("%.2f", s.Throughput)})
+	}
+	if len(run.Results) > 0 {
+		earliest, latest := timeRange(run.Results)
+		params = append(params,
+			keyValue{"Earliest", earliest.Format(time.RFC3339Nano)},
+			keyValue{"Latest", latest.Format(time.RFC3339Nano)},
+		)
+	}
+	return params
+}
+
+// timeRange gives back the earliest and the latest timestamps of the given results.
+func timeRange(results []export.Result) (earliest, latest time.Time) {
+	if len(results) == 0 {
+		return
+	}
+	earliest, latest = results[0].Timestamp, results[0].Timestamp
+	for _, r := range results[1:] {
+		if r.Timestamp.Before(earliest) {
+			earliest = r.Timestamp
+		}
+		if r.Timestamp.After(latest) {
+			latest = r.Timestamp
+		}
+	}
+	return
+}
+
+// statusCodes gives back the number of results of each status code, sorted by status code.
+func statusCodes(results []export.Result) []codeCount {
+	counts := make(map[uint16]int)
+	for _, r := range results {
+		counts[r.StatusCode]++
+	}
+	codes := make([]codeCount, 0, len(counts))
+	for code, count := range counts {
+		codes = append(codes, codeCount{Code: code, Count: count})
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// errorCounts gives back the number of the failed results of each target and status code, sorted by count.
+func errorCounts(results []export.Result) []errorCount {
+	type key struct {
+		method, url string
+		code        uint16
+	}
+	counts := make(map[key]int)
+	for _, r := range results {
+		if r.StatusCode >= 200 && r.StatusCode < 400 {
+			continue
+		}
+		counts[key{method: r.Method, url: r.URL, code: r.StatusCode}]++
+	}
+	errs := make([]errorCount, 0, len(counts))
+	for k, count := range counts {
+		errs = append(errs, errorCount{Method: k.method, URL: k.url, Code: k.code, Count: count})
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Count != errs[j].Count {
+			return errs[i].Count > errs[j].Count
+		}
+		if errs[i].URL != errs[j].URL {
+			return errs[i].URL < errs[j].URL
+		}
+		return errs[i].Code < errs[j].Code
+	})
+	return errs
+}
+
+// latencySeries gives back the latencies in milliseconds over the seconds since the earliest.
+// If there are too many, the results are grouped in order and the highest latency of each group is taken,
+// so that spikes remain visible.
+func latencySeries(results []export.Result, earliest time.Time) series {
+	sorted := sortedByTime(results)
+	groupSize := int(math.Ceil(float64(len(sorted)) / maxChartPoints))
+	s := series{name: "latency", color: "#5fffff"}
+	for i := 0; i < len(sorted); i += groupSize {
+		end := min(i+groupSize, len(sorted))
+		var highest float64
+		for _, r := range sorted[i:end] {
+			if finite(r.LatencyNS) {
+				highest = math.Max(highest, r.LatencyNS)
+			}
+		}
+		x := sorted[i].Timestamp.Sub(earliest).Seconds()
+		s.points = append(s.points, [2]float64{x, highest / float64(time.Millisecond)})
+	}
+	return s
+}
+
+// percentileSeries gives back the percentiles in milliseconds of the results within each time window.
+func percentileSeries(results []export.Result, earliest time.Time) []series {
+	all := []series{
+		{name: "p50", color: "#ff00ff"},
+		{name: "p90", color: "#d7d700"},
+		{name: "p95", color: "#00d700"},
+		{name: "p99", color: "#00afd7"},
+	}
+	qs := []float64{0.50, 0.90, 0.95, 0.99}
+	_, latest := timeRange(results)
+	length := latest.Sub(earliest)
+	windows := min(len(results), 100)
+	if windows == 0 {
+		return all
+	}
+	buckets := make([][]float64, windows)
+	for _, r := range results {
+		if !finite(r.LatencyNS) {
+			continue
+		}
+		i := 0
+		if length > 0 {
+			i = int(float64(r.Timestamp.Sub(earliest)) / float64(length) * float64(windows))
+		}
+		if i >= windows {
+			i = windows - 1
+		}
+		buckets[i] = append(buckets[i], r.LatencyNS)
+	}
+	for i, latencies := range buckets {
+		if len(latencies) == 0 {
+			continue
+		}
+		sort.Float64s(latencies)
+		x := (float64(i) + 0.5) / float64(windows) * length.Seconds()
+		for j, q := range qs {
+			all[j].points = append(all[j].points, [2]float64{x, quantile(latencies, q) / float64(time.Millisecond)})
+		}
+	}
+	return all
+}
+
+// histogram gives back the bars with the same width in milliseconds, from zero to the highest latency.
+func histogram(results []export.Result) []bar {
+	var highest float64
+	for _, r := range results {
+		if finite(r.LatencyNS) {
+			highest = math.Max(highest, r.LatencyNS/float64(time.Millisecond))
+		}
+	}
+	if highest == 0 {
+		return nil
+	}
+	width := highest / histogramBins
+	bars := make([]bar, histogramBins)
+	for i := range bars {
+		bars[i].min = width * float64(i)
+		bars[i].max = width * float64(i+1)
+	}
+	for _, r := range results {
+		if !finite(r.LatencyNS) {
+			continue
+		}
+		i := int(r.LatencyNS / float64(time.Millisecond) / width)
+		if i >= histogramBins {
+			i = histogramBins - 1
+		}
+		bars[i].count++
+	}
+	return bars
+}
+
+func sortedByTime(results []export.Result) []export.Result {
+	sorted := append([]export.Result(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	return sorted
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nakabonne/ali/export"
+)
+
+func TestWriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteHTML(&b, filepath.Join("..", "testdata", "export", "basic"), Options{}))
+	out := b.String()
+
+	assert.Contains(t, out, "<h2>Run 00000000-0000-0000-0000-000000000000</h2>")
+	assert.Contains(t, out, "<tr><th>Target</th><td>GET https://example.com/</td></tr>")
+	assert.Equal(t, 3, strings.Count(out, "<svg "), "latency, percentiles and histogram charts")
+	assert.Contains(t, out, "<tr><td>GET https://example.com/</td><td>500</td><td class=\"num\">1</td></tr>")
+	assert.NotRegexp(t, `(src|href)="https?://`, out, "no external resources should be referenced")
+
+	embedded := regexp.MustCompile(`<script type="application/json" class="results">(.*)</script>`).FindStringSubmatch(out)
+	require.Len(t, embedded, 2)
+	var results []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(embedded[1]), &results))
+	assert.Len(t, results, 3)
+
+	assert.Error(t, WriteHTML(&b, filepath.Join("..", "testdata", "export", "empty"), Options{}))
+}
+
+func TestHistogram(t *testing.T) {
+	assert.Nil(t, histogram(nil))
+
+	results := []export.Result{
+		{LatencyNS: float64(time.Millisecond)},
+		{LatencyNS: float64(20 * time.Millisecond)},
+		{LatencyNS: float64(20 * time.Millisecond)},
+	}
+	bars := histogram(results)
+	require.Len(t, bars, histogramBins)
+	assert.Equal(t, bar{min: 1, max: 2, count: 1}, bars[1])
+	assert.Equal(t, bar{min: 19, max: 20, count: 2}, bars[histogramBins-1])
+}
+
+func TestPercentileSeries(t *testing.T) {
+	began := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	results := []export.Result{
+		{Timestamp: began, LatencyNS: float64(time.Millisecond)},
+		{Timestamp: began.Add(time.Second), LatencyNS: float64(3 * time.Millisecond)},
+	}
+	all := percentileSeries(results, began)
+	require.Len(t, all, 4)
+	assert.Equal(t, "p50", all[0].name)
+	assert.Equal(t, [][2]float64{{0.25, 1}, {0.75, 3}}, all[0].points)
+}
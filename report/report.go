@@ -0,0 +1,197 @@
+// Package report renders the results exported by ali, without attacking.
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nakabonne/ali/export"
+)
+
+type Options struct {
+	// ID is the id of the only run to be rendered. All runs are rendered if empty.
+	ID string
+}
+
+// Write reads the runs in the given export directory, and then writes a table per run into w.
+// Percentiles are recomputed from the latencies of the results.
+func Write(w io.Writer, dir string, opts Options) error {
+	runs, err := readRuns(dir, opts.ID)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no runs found in %q", dir)
+	}
+	for i, run := range runs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := writeRun(w, run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRuns(dir, id string) ([]*export.RunRecord, error) {
+	reader := export.NewFileReader(dir)
+	if id == "" {
+		return reader.Runs()
+	}
+	run, err := reader.Run(id)
+	if err != nil {
+		return nil, err
+	}
+	return []*export.RunRecord{run}, nil
+}
+
+// stats holds the metrics recomputed from results.
+type stats struct {
+	label    string
+	requests int
+	success  float64
+	mean     time.Duration
+	p50      time.Duration
+	p90      time.Duration
+	p95      time.Duration
+	p99      time.Duration
+	max      time.Duration
+}
+
+func newStats(label string, results []export.Result) stats {
+	s := stats{label: label, requests: len(results)}
+	latencies := make([]float64, 0, len(results))
+	var successes int
+	var sum float64
+	for _, r := range results {
+		// Same as vegeta, responses with 2xx and 3xx are successful.
+		if r.StatusCode >= 200 && r.StatusCode < 400 {
+			successes++
+		}
+		if math.IsNaN(r.LatencyNS) || math.IsInf(r.LatencyNS, 0) {
+			continue
+		}
+		latencies = append(latencies, r.LatencyNS)
+		sum += r.LatencyNS
+	}
+	if len(results) > 0 {
+		s.success = float64(successes) / float64(len(results))
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+	sort.Float64s(latencies)
+	s.mean = time.Duration(sum / float64(len(latencies)))
+	s.p50 = time.Duration(quantile(latencies, 0.50))
+	s.p90 = time.Duration(quantile(latencies, 0.90))
+	s.p95 = time.Duration(quantile(latencies, 0.95))
+	s.p99 = time.Duration(quantile(latencies, 0.99))
+	s.max = time.Duration(latencies[len(latencies)-1])
+	return s
+}
+
+// quantile gives back the q-th quantile of the given sorted values, with the nearest-rank method.
+func quantile(sorted []float64, q float64) float64 {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// targetStats gives back the stats of each target, sorted by URL and method.
+func targetStats(results []export.Result) []stats {
+	type key struct{ method, url string }
+	byTarget := make(map[key][]export.Result)
+	for _, r := range results {
+		k := key{method: r.Method, url: r.URL}
+		byTarget[k] = append(byTarget[k], r)
+	}
+	keys := make([]key, 0, len(byTarget))
+	for k := range byTarget {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].url != keys[j].url {
+			return keys[i].url < keys[j].url
+		}
+		return keys[i].method < keys[j].method
+	})
+	all := make([]stats, 0, len(keys))
+	for _, k := range keys {
+		all = append(all, newStats(k.method+" "+k.url, byTarget[k]))
+	}
+	return all
+}
+
+func writeRun(w io.Writer, run *export.RunRecord) error {
+	fmt.Fprintf(w, "Run: %s\n", run.ID)
+	if len(run.Results) > 0 {
+		earliest, latest := run.Results[0].Timestamp, run.Results[0].Timestamp
+		for _, r := range run.Results[1:] {
+			if r.Timestamp.Before(earliest) {
+				earliest = r.Timestamp
+			}
+			if r.Timestamp.After(latest) {
+				latest = r.Timestamp
+			}
+		}
+		fmt.Fprintf(w, "Earliest: %s\n", earliest.Format(time.RFC3339Nano))
+		fmt.Fprintf(w, "Latest: %s\n", latest.Format(time.RFC3339Nano))
+	}
+	if s := run.Summary; s != nil {
+		fmt.Fprintf(w, "Parameters: rate=%d, duration=%v\n", s.Parameters.Rate, time.Duration(s.Parameters.DurationSeconds*float64(time.Second)))
+	} else {
+		fmt.Fprintln(w, "Parameters: unknown (no summary found)")
+	}
+
+	rows := targetStats(run.Results)
+	if len(rows) > 1 {
+		rows = append(rows, newStats("Total", run.Results))
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tREQUESTS\tSUCCESS\tMEAN\tP50\tP90\tP95\tP99\tMAX")
+	for _, s := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f%%\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			s.label,
+			s.requests,
+			s.success*100,
+			round(s.mean),
+			round(s.p50),
+			round(s.p90),
+			round(s.p95),
+			round(s.p99),
+			round(s.max),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write the table: %w", err)
+	}
+
+	codes := make(map[uint16]int)
+	for _, r := range run.Results {
+		codes[r.StatusCode]++
+	}
+	keys := make([]int, 0, len(codes))
+	for code := range codes {
+		keys = append(keys, int(code))
+	}
+	sort.Ints(keys)
+	parts := make([]string, 0, len(keys))
+	for _, code := range keys {
+		parts = append(parts, fmt.Sprintf("%d=%d", code, codes[uint16(code)]))
+	}
+	fmt.Fprintf(w, "Status codes: %s\n", strings.Join(parts, ", "))
+	return nil
+}
+
+// round rounds the given latency to microseconds for readability.
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
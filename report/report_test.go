@@ -0,0 +1,60 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nakabonne/ali/export"
+)
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, Write(&b, filepath.Join("..", "testdata", "export", "basic"), Options{}))
+	assert.Equal(t, `Run: 00000000-0000-0000-0000-000000000000
+Earliest: 2021-03-13T15:20:43+09:00
+Latest: 2021-03-13T15:20:43.041+09:00
+Parameters: rate=50, duration=2s
+TARGET                    REQUESTS  SUCCESS  MEAN       P50     P90       P95       P99       MAX
+GET https://example.com/  3         66.67%   332.875ms  44.9ms  935.49ms  935.49ms  935.49ms  935.49ms
+Status codes: 200=2, 500=1
+`, b.String())
+}
+
+func TestWriteMultipleRuns(t *testing.T) {
+	dir := t.TempDir()
+	exporter := export.NewFileExporter(dir)
+	timestamp := time.Date(2021, 3, 13, 6, 20, 43, 0, time.UTC)
+	for _, id := range []string{"run-1", "run-2"} {
+		run, err := exporter.StartRun(export.Meta{ID: id, Method: "GET"})
+		require.NoError(t, err)
+		require.NoError(t, run.WriteResult(export.Result{Timestamp: timestamp, LatencyNS: 1e6, URL: "http://host.xz/a", StatusCode: 200}))
+		require.NoError(t, run.WriteResult(export.Result{Timestamp: timestamp, LatencyNS: 3e6, URL: "http://host.xz/b", StatusCode: 500}))
+		require.NoError(t, run.Close(export.Summary{Parameters: export.ParametersSummary{Rate: 10, DurationSeconds: 1}}))
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, Write(&b, dir, Options{}))
+	assert.Contains(t, b.String(), "Run: run-1\n")
+	assert.Contains(t, b.String(), "Run: run-2\n")
+
+	b.Reset()
+	require.NoError(t, Write(&b, dir, Options{ID: "run-2"}))
+	assert.Equal(t, `Run: run-2
+Earliest: 2021-03-13T06:20:43Z
+Latest: 2021-03-13T06:20:43Z
+Parameters: rate=10, duration=1s
+TARGET                REQUESTS  SUCCESS  MEAN  P50  P90  P95  P99  MAX
+GET http://host.xz/a  1         100.00%  1ms   1ms  1ms  1ms  1ms  1ms
+GET http://host.xz/b  1         0.00%    3ms   3ms  3ms  3ms  3ms  3ms
+Total                 2         50.00%   2ms   1ms  3ms  3ms  3ms  3ms
+Status codes: 200=1, 500=1
+`, b.String())
+
+	assert.Error(t, Write(&b, dir, Options{ID: "run-3"}))
+	assert.Error(t, Write(&b, filepath.Join("..", "testdata", "export", "empty"), Options{}))
+}
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartWidth   = 800
+	chartHeight  = 240
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 20
+	marginBottom = 30
+	// maxChartPoints is the maximum number of points a series is drawn with, to keep the file small.
+	maxChartPoints = 1000
+)
+
+// series is a line on the chart, whose points are pairs of x and y.
+type series struct {
+	name   string
+	color  string
+	points [][2]float64
+}
+
+// lineChart renders the given series as an inline SVG, with the axes fitting all the points.
+func lineChart(title, xUnit, yUnit string, all []series) template.HTML {
+	var maxX, maxY float64
+	for _, s := range all {
+		for _, p := range s.points {
+			maxX = math.Max(maxX, p[0])
+			maxY = math.Max(maxY, p[1])
+		}
+	}
+	if maxX == 0 {
+		maxX = 1
+	}
+	if maxY == 0 {
+		maxY = 1
+	}
+	var b strings.Builder
+	openSVG(&b, title)
+	drawAxes(&b, maxX, maxY, xUnit, yUnit)
+	for i, s := range all {
+		coords := make([]string, 0, len(s.points))
+		for _, p := range s.points {
+			x, y := scale(p[0], p[1], maxX, maxY)
+			coords = append(coords, formatFloat(x)+","+formatFloat(y))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, s.color, strings.Join(coords, " "))
+		// Legends are put at the top right.
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s" font-size="12">%s</text>`,
+			chartWidth-marginRight-40*(len(all)-i), marginTop-6, s.color, html.EscapeString(s.name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// bar is a bar in the histogram, which ranges from min to max.
+type bar struct {
+	min, max float64
+	count    int
+}
+
+// barChart renders the given bars as an inline SVG histogram.
+func barChart(title, xUnit string, bars []bar) template.HTML {
+	var maxCount int
+	for _, bar := range bars {
+		if bar.count > maxCount {
+			maxCount = bar.count
+		}
+	}
+	maxX := 1.0
+	if len(bars) > 0 {
+		maxX = bars[len(bars)-1].max
+	}
+	maxY := math.Max(float64(maxCount), 1)
+	var b strings.Builder
+	openSVG(&b, title)
+	drawAxes(&b, maxX, maxY, xUnit, "count")
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	for _, bar := range bars {
+		x0, y := scale(bar.min, float64(bar.count), maxX, maxY)
+		x1, _ := scale(bar.max, 0, maxX, maxY)
+		width := math.Max(x1-x0-1, plotWidth/1000)
+		fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" fill="#5fafff"><title>%s-%s %s: %d</title></rect>`,
+			formatFloat(x0), formatFloat(y), formatFloat(width), formatFloat(float64(chartHeight-marginBottom)-y),
+			formatFloat(bar.min), formatFloat(bar.max), html.EscapeString(xUnit), bar.count)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func openSVG(b *strings.Builder, title string) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s">`,
+		chartWidth, chartHeight, chartWidth, chartHeight, html.EscapeString(title))
+}
+
+// drawAxes draws the axes with the labels at the beginning, the middle and the end.
+func drawAxes(b *strings.Builder, maxX, maxY float64, xUnit, yUnit string) {
+	left, bottom := marginLeft, chartHeight-marginBottom
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, left, marginTop, left, bottom)
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, left, bottom, chartWidth-marginRight, bottom)
+	for _, r := range []float64{0, 0.5, 1} {
+		x, _ := scale(maxX*r, 0, maxX, maxY)
+		_, y := scale(0, maxY*r, maxX, maxY)
+		fmt.Fprintf(b, `<text x="%s" y="%d" font-size="11" text-anchor="middle">%s</text>`, formatFloat(x), bottom+15, formatFloat(maxX*r))
+		fmt.Fprintf(b, `<text x="%d" y="%s" font-size="11" text-anchor="end">%s</text>`, left-5, formatFloat(y+4), formatFloat(maxY*r))
+	}
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartWidth-marginRight, chartHeight-2, html.EscapeString(xUnit))
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-size="11">%s</text>`, 2, marginTop-6, html.EscapeString(yUnit))
+}
+
+// scale converts the given values into the coordinates within the plot area.
+func scale(x, y, maxX, maxY float64) (float64, float64) {
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	plotHeight := float64(chartHeight - marginTop - marginBottom)
+	return marginLeft + x/maxX*plotWidth, float64(chartHeight-marginBottom) - y/maxY*plotHeight
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/nakabonne/ali/attacker"
+)
+
+// runRerun runs the "rerun" subcommand, which attacks again with the config exported along with a run by "--export-to".
+func runRerun(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ali rerun", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	c := &cli{
+		stdout: stdout,
+		stderr: stderr,
+	}
+	var headers []string
+	fs.StringArrayVarP(&headers, "header", "H", []string{}, `A request header like "Authorization: Bearer xxx", set in place of the redacted one with the same name. Can be used multiple times.`)
+	c.addSessionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, `Usage:
+  ali rerun [flags] <config file>
+
+Flags:
+%s
+Examples:
+  ali rerun ./results/config-00000000-0000-0000-0000-000000000000.json
+  ali rerun --header "Authorization: Bearer xxx" --no-tui ./results/config-00000000-0000-0000-0000-000000000000.json
+`, fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "a config file is required")
+		fs.Usage()
+		return 1
+	}
+
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to open %q: %v\n", path, err)
+		return 1
+	}
+	defer f.Close()
+	config, err := attacker.ReadConfig(f)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read config from %q: %v\n", path, err)
+		return 1
+	}
+	for _, hdr := range headers {
+		key, val, err := parseHeader(hdr)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			fs.Usage()
+			return 1
+		}
+		config.SetRedactedHeader(key, val)
+	}
+	opts, err := config.Options()
+	if errors.Is(err, attacker.ErrRedacted) {
+		fmt.Fprintf(stderr, "%v; give the values with \"--header\" or edit %q\n", err, path)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	opts.Config = config
+
+	targetLabel := config.Target
+	if targetLabel == "" {
+		targetLabel = path
+	}
+	return c.attack(config.Target, targetLabel, opts)
+}
@@ -11,6 +11,15 @@ func (f *FakeStorage) Insert(_ *Result) error {
 	return f.err
 }
 
-func (f *FakeStorage) Select(_ string, _, _ time.Time) ([]float64, error) {
+func (f *FakeStorage) Select(_ string, _, _ time.Time, _ ...Label) ([]float64, error) {
 	return f.Values, f.err
 }
+
+// SelectPoints gives back the values along with the timestamps which are their indexes in seconds.
+func (f *FakeStorage) SelectPoints(_ string, _, _ time.Time, _ ...Label) ([]DataPoint, error) {
+	points := make([]DataPoint, len(f.Values))
+	for i, v := range f.Values {
+		points[i] = DataPoint{Value: v, Timestamp: int64(i) * int64(time.Second)}
+	}
+	return points, f.err
+}
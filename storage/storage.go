@@ -3,6 +3,10 @@ package storage
 import (
 	"errors"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nakabonne/tstorage"
@@ -14,8 +18,39 @@ const (
 	P90MetricName     = "p90"
 	P95MetricName     = "p95"
 	P99MetricName     = "p99"
+	// ErrorMetricName is 1 if the request failed in the same way as vegeta, where 2xx and 3xx are successful, otherwise 0.
+	// The error_ratio abort condition reads it.
+	ErrorMetricName = "error"
+	// FailedMetricName is 1 if the status code isn't 2xx, including the transport errors which got no response, otherwise 0.
+	// Unlike ErrorMetricName, 3xx are counted as failures. The error charts read it.
+	FailedMetricName = "failed"
+	// IssuedMetricName is 1 for each request issued, at the time it was issued.
+	IssuedMetricName = "issued"
+	// SuccessMetricName is 1 if the request succeeded in the same way as ErrorMetricName, otherwise 0,
+	// at the time the response came in.
+	SuccessMetricName = "success"
+	// TargetRateMetricName is the rate per second the attack was aiming at when each result came in.
+	TargetRateMetricName = "target_rate"
+	// StageMetricName is the 1-based index of the stage each request was issued in.
+	StageMetricName = "stage"
+
+	// CodeLabelName is the label of the data points of each request, holding the status code.
+	CodeLabelName = "code"
+	// ClassLabelName is the label of the data points of each request, holding the status class like "2xx".
+	ClassLabelName = "class"
+	// ClassNoResponse is the status class of the requests which got no response, whose code is 0.
+	ClassNoResponse = "none"
 )
 
+// StatusClasses are all the status classes in order.
+var StatusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx", ClassNoResponse}
+
+// Label is a label of the data points, which is matched on selection.
+type Label = tstorage.Label
+
+// DataPoint is a value along with its timestamp in unix time in nanoseconds.
+type DataPoint = tstorage.DataPoint
+
 // Storage provides goroutine safe capabilities of insertion into and retrieval from the time-series storage.
 // Backed by "nakabonne/tstorage"
 type Storage interface {
@@ -28,7 +63,11 @@ type Writer interface {
 }
 
 type Reader interface {
-	Select(metric string, start, end time.Time) ([]float64, error)
+	// Select gives back the values of the given metric within the range, in the order of their timestamps.
+	// If labels are given, only the data points having all of them are selected.
+	Select(metric string, start, end time.Time, labels ...Label) ([]float64, error)
+	// SelectPoints is the same as Select, except that the timestamps are given back along with the values.
+	SelectPoints(metric string, start, end time.Time, labels ...Label) ([]DataPoint, error)
 }
 
 // Result contains the results of a single HTTP request.
@@ -40,6 +79,10 @@ type Result struct {
 	P90       time.Duration
 	P95       time.Duration
 	P99       time.Duration
+	// Stage is the 1-based index of the stage, zero if the attack has no stages.
+	Stage int
+	// TargetRate is the rate per second the attack was aiming at, zero if unknown.
+	TargetRate float64
 }
 
 func NewStorage(partitionDuration time.Duration) (Storage, error) {
@@ -50,11 +93,20 @@ func NewStorage(partitionDuration time.Duration) (Storage, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &storage{backend: s}, nil
+	return &storage{backend: s, series: make(map[string]map[string][]Label), latest: make(map[string]int64)}, nil
 }
 
 type storage struct {
 	backend tstorage.Storage
+
+	// series are the label sets of each labeled metric, since the backend can select only a single series at a time.
+	mu     sync.RWMutex
+	series map[string]map[string][]Label
+
+	// latest are the latest timestamps of the metrics counted per time bucket,
+	// since the backend drops the data points older than the latest one from selection.
+	latestMu sync.Mutex
+	latest   map[string]int64
 }
 
 // Insert writes the given result to the backend storage.
@@ -62,23 +114,36 @@ type storage struct {
 func (s *storage) Insert(result *Result) error {
 	// Convert timestamp into unix time in nanoseconds.
 	timestamp := result.Timestamp.UnixNano()
-	// TODO: Think about how to handle code
-	/*
-		labels := []tstorage.Label{
-			{
-				Name:  codeLabelName,
-				Value: strconv.Itoa(int(result.Code)),
-			},
-		}
-	*/
+	labels := []Label{
+		{Name: ClassLabelName, Value: StatusClass(result.Code)},
+		{Name: CodeLabelName, Value: strconv.Itoa(int(result.Code))},
+	}
+	s.addSeries(labels, LatencyMetricName, ErrorMetricName, FailedMetricName)
 	rows := []tstorage.Row{
 		{
 			Metric: LatencyMetricName,
+			Labels: labels,
 			DataPoint: tstorage.DataPoint{
 				Timestamp: timestamp,
 				Value:     float64(result.Latency.Milliseconds()),
 			},
 		},
+		{
+			Metric: ErrorMetricName,
+			Labels: labels,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: timestamp,
+				Value:     errorValue(result.Code),
+			},
+		},
+		{
+			Metric: FailedMetricName,
+			Labels: labels,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: timestamp,
+				Value:     failedValue(result.Code),
+			},
+		},
 		{
 			Metric: P50MetricName,
 			DataPoint: tstorage.DataPoint{
@@ -108,15 +173,114 @@ func (s *storage) Insert(result *Result) error {
 			},
 		},
 	}
+	rows = append(rows,
+		tstorage.Row{
+			Metric: IssuedMetricName,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: s.inOrder(IssuedMetricName, timestamp),
+				Value:     1,
+			},
+		},
+		tstorage.Row{
+			Metric: SuccessMetricName,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: s.inOrder(SuccessMetricName, result.Timestamp.Add(result.Latency).UnixNano()),
+				Value:     1 - errorValue(result.Code),
+			},
+		},
+	)
+	if result.TargetRate > 0 {
+		rows = append(rows, tstorage.Row{
+			Metric: TargetRateMetricName,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: timestamp,
+				Value:     result.TargetRate,
+			},
+		})
+	}
+	if result.Stage > 0 {
+		rows = append(rows, tstorage.Row{
+			Metric: StageMetricName,
+			DataPoint: tstorage.DataPoint{
+				Timestamp: timestamp,
+				Value:     float64(result.Stage),
+			},
+		})
+	}
 	return s.backend.InsertRows(rows)
 }
 
-func (s *storage) Select(metric string, start, end time.Time) ([]float64, error) {
-	// Convert timestamp into unix time in nanoseconds.
-	points, err := s.backend.Select(metric, nil, start.UnixNano(), end.UnixNano())
-	if errors.Is(err, tstorage.ErrNoDataPoints) {
-		return []float64{}, nil
+// inOrder gives back the given timestamp of the given metric,
+// or the one right after the latest one if it's not newer, so that no data point is dropped.
+// It skews the data points which come in out of order: the results come in as the responses do, so a request
+// issued before a faster one gets moved to the issue time of that one, which can be in the next second.
+// The skew is at most the difference of their latencies, and the total count is kept.
+func (s *storage) inOrder(metric string, timestamp int64) int64 {
+	s.latestMu.Lock()
+	defer s.latestMu.Unlock()
+	if latest, ok := s.latest[metric]; ok && timestamp <= latest {
+		timestamp = latest + 1
+	}
+	s.latest[metric] = timestamp
+	return timestamp
+}
+
+// errorValue gives back 1 if the given code isn't a successful one, in the same way as vegeta.
+func errorValue(code uint16) float64 {
+	if code >= 200 && code < 400 {
+		return 0
+	}
+	return 1
+}
+
+// failedValue gives back 1 if the given code isn't 2xx.
+func failedValue(code uint16) float64 {
+	if code >= 200 && code < 300 {
+		return 0
+	}
+	return 1
+}
+
+// StatusClass gives back the class of the given status code like "2xx", or ClassNoResponse if it's 0.
+func StatusClass(code uint16) string {
+	if code == 0 {
+		return ClassNoResponse
+	}
+	return strconv.Itoa(int(code/100)) + "xx"
+}
+
+// addSeries remembers the given label set of the given metrics. The labels have to be sorted by name.
+func (s *storage) addSeries(labels []Label, metrics ...string) {
+	key := labelsKey(labels)
+	s.mu.RLock()
+	_, ok := s.series[metrics[0]][key]
+	s.mu.RUnlock()
+	if ok {
+		return
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range metrics {
+		if s.series[m] == nil {
+			s.series[m] = make(map[string][]Label)
+		}
+		s.series[m][key] = append([]Label(nil), labels...)
+	}
+}
+
+func labelsKey(labels []Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte('=')
+		b.WriteString(l.Value)
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func (s *storage) Select(metric string, start, end time.Time, labels ...Label) ([]float64, error) {
+	points, err := s.SelectPoints(metric, start, end, labels...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,3 +290,63 @@ func (s *storage) Select(metric string, start, end time.Time) ([]float64, error)
 	}
 	return values, nil
 }
+
+func (s *storage) SelectPoints(metric string, start, end time.Time, labels ...Label) ([]DataPoint, error) {
+	s.mu.RLock()
+	series, labeled := s.series[metric]
+	var matched [][]Label
+	for _, ls := range series {
+		if hasLabels(ls, labels) {
+			// Copied since the backend sorts them in place.
+			matched = append(matched, append([]Label(nil), ls...))
+		}
+	}
+	s.mu.RUnlock()
+	if !labeled {
+		if len(labels) > 0 {
+			return []DataPoint{}, nil
+		}
+		matched = [][]Label{nil}
+	}
+
+	var points []DataPoint
+	for _, ls := range matched {
+		// Convert timestamp into unix time in nanoseconds.
+		ps, err := s.backend.Select(metric, ls, start.UnixNano(), end.UnixNano())
+		if errors.Is(err, tstorage.ErrNoDataPoints) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range ps {
+			points = append(points, *p)
+		}
+	}
+	if len(matched) > 1 {
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].Timestamp < points[j].Timestamp
+		})
+	}
+	if points == nil {
+		points = []DataPoint{}
+	}
+	return points, nil
+}
+
+// hasLabels reports whether all of want are in labels.
+func hasLabels(labels, want []Label) bool {
+	for _, w := range want {
+		found := false
+		for _, l := range labels {
+			if l == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		code uint16
+		want string
+	}{
+		{code: 0, want: ClassNoResponse},
+		{code: 101, want: "1xx"},
+		{code: 200, want: "2xx"},
+		{code: 302, want: "3xx"},
+		{code: 404, want: "4xx"},
+		{code: 503, want: "5xx"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, StatusClass(tt.code))
+	}
+}
+
+func TestSelectWithLabels(t *testing.T) {
+	s, err := NewStorage(time.Hour)
+	require.NoError(t, err)
+	start := time.Unix(1600000000, 0)
+	for i, code := range []uint16{200, 500, 201, 0, 200} {
+		require.NoError(t, s.Insert(&Result{
+			Code:      code,
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+			P50:       time.Millisecond,
+		}))
+	}
+	end := start.Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		metric string
+		labels []Label
+		want   []float64
+	}{
+		{
+			name:   "all series in order",
+			metric: LatencyMetricName,
+			want:   []float64{1, 2, 3, 4, 5},
+		},
+		{
+			name:   "by class",
+			metric: LatencyMetricName,
+			labels: []Label{{Name: ClassLabelName, Value: "2xx"}},
+			want:   []float64{1, 3, 5},
+		},
+		{
+			name:   "by code",
+			metric: ErrorMetricName,
+			labels: []Label{{Name: CodeLabelName, Value: "500"}},
+			want:   []float64{1},
+		},
+		{
+			name:   "by class and code",
+			metric: LatencyMetricName,
+			labels: []Label{{Name: ClassLabelName, Value: "2xx"}, {Name: CodeLabelName, Value: "201"}},
+			want:   []float64{3},
+		},
+		{
+			name:   "no response",
+			metric: ErrorMetricName,
+			labels: []Label{{Name: ClassLabelName, Value: ClassNoResponse}},
+			want:   []float64{1},
+		},
+		{
+			name:   "failed requests",
+			metric: FailedMetricName,
+			want:   []float64{0, 1, 0, 1, 0},
+		},
+		{
+			name:   "no matching series",
+			metric: LatencyMetricName,
+			labels: []Label{{Name: ClassLabelName, Value: "4xx"}},
+			want:   []float64{},
+		},
+		{
+			name:   "unlabeled metric",
+			metric: P50MetricName,
+			want:   []float64{1, 1, 1, 1, 1},
+		},
+		{
+			name:   "labels on unlabeled metric",
+			metric: P50MetricName,
+			labels: []Label{{Name: ClassLabelName, Value: "2xx"}},
+			want:   []float64{},
+		},
+		{
+			name:   "unknown metric",
+			metric: "unknown",
+			want:   []float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Select(tt.metric, start, end, tt.labels...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	points, err := s.SelectPoints(LatencyMetricName, start, end, Label{Name: ClassLabelName, Value: "2xx"})
+	require.NoError(t, err)
+	assert.Equal(t, []DataPoint{
+		{Value: 1, Timestamp: start.UnixNano()},
+		{Value: 3, Timestamp: start.Add(2 * time.Second).UnixNano()},
+		{Value: 5, Timestamp: start.Add(4 * time.Second).UnixNano()},
+	}, points)
+}
+
+func TestInsertRateMetrics(t *testing.T) {
+	s, err := NewStorage(time.Hour)
+	require.NoError(t, err)
+	start := time.Unix(1600000000, 0)
+	// The later request comes in first since it responded faster.
+	require.NoError(t, s.Insert(&Result{
+		Code:       200,
+		Timestamp:  start.Add(500 * time.Millisecond),
+		Latency:    10 * time.Millisecond,
+		TargetRate: 10,
+	}))
+	require.NoError(t, s.Insert(&Result{
+		Code:      500,
+		Timestamp: start,
+		Latency:   1500 * time.Millisecond,
+	}))
+	end := start.Add(time.Minute)
+
+	issued, err := s.SelectPoints(IssuedMetricName, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []DataPoint{
+		{Value: 1, Timestamp: start.Add(500 * time.Millisecond).UnixNano()},
+		{Value: 1, Timestamp: start.Add(500*time.Millisecond).UnixNano() + 1},
+	}, issued)
+
+	success, err := s.SelectPoints(SuccessMetricName, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []DataPoint{
+		{Value: 1, Timestamp: start.Add(510 * time.Millisecond).UnixNano()},
+		{Value: 0, Timestamp: start.Add(1500 * time.Millisecond).UnixNano()},
+	}, success)
+
+	targetRate, err := s.Select(TargetRateMetricName, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{10}, targetRate)
+}